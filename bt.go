@@ -0,0 +1,184 @@
+package gf
+
+import (
+	"fmt"
+)
+
+// Binary tower fields of Wiedemann as used in Binius
+// τ_0 = GF(2), τ_1 = τ_0[X_0] / (X_0^2 + X_0 + 1)
+// τ_(k+1) = τ_k[X_k] / (X_k^2 + X_(k-1) X_k + 1)
+// so that τ_k is GF(2^(2^k)). Element of τ_k is a_0 + a_1 X_(k-1) with a_0, a_1
+// in τ_(k-1), kept in 2^k bits with a_0 in the lower half. Hence bits of an
+// element are its coordinates in the monomial basis of X_0, ..., X_(k-1) and
+// an element of a lower level is embedded as it is.
+//
+// Elements of τ_k for k <= 6 are kept in uint64 words and a word is also
+// seen as 64 / 2^k packed elements in lanes of 2^k bits. Arithmetic is done
+// lane by lane, so that same functions serve both a single element in the
+// lowest lane and packed elements. τ_7 is kept in two words.
+
+// btMaxLevel is the highest level kept in a single word.
+const btMaxLevel = 6
+
+// btLowMask has the lower half of every lane of level k set.
+var btLowMask = [btMaxLevel + 1]uint64{
+	0,
+	0x5555555555555555,
+	0x3333333333333333,
+	0x0f0f0f0f0f0f0f0f,
+	0x00ff00ff00ff00ff,
+	0x0000ffff0000ffff,
+	0x00000000ffffffff,
+}
+
+// btSplit splits every lane of level k into halves of level k - 1.
+func btSplit(k int, a uint64) (uint64, uint64) {
+	m := btLowMask[k]
+	return a & m, a >> (1 << (k - 1)) & m
+}
+
+// btJoin joins halves of level k - 1 into lanes of level k.
+func btJoin(k int, a0, a1 uint64) uint64 {
+	return a0 | a1<<(1<<(k-1))
+}
+
+// btMul multiplies lanes of level k with Karatsuba where
+// (a_0 + a_1 X)(b_0 + b_1 X) = a_0 b_0 + a_1 b_1 + (a_0 b_1 + a_1 b_0 + a_1 b_1 X') X
+// and X' is the generator one level below.
+func btMul(k int, a, b uint64) uint64 {
+	if k == 0 {
+		return a & b
+	}
+	a0, a1 := btSplit(k, a)
+	b0, b1 := btSplit(k, b)
+	t0 := btMul(k-1, a0, b0)
+	t2 := btMul(k-1, a1, b1)
+	t1 := btMul(k-1, a0^a1, b0^b1)
+	return btJoin(k, t0^t2, t1^t0^t2^btMulX(k-1, t2))
+}
+
+// btMulX multiplies lanes of level k by the generator X_(k-1), which is one
+// at level zero. (a_0 + a_1 X) X = a_1 + (a_0 + a_1 X') X
+func btMulX(k int, a uint64) uint64 {
+	if k == 0 {
+		return a
+	}
+	a0, a1 := btSplit(k, a)
+	return btJoin(k, a1, a0^btMulX(k-1, a1))
+}
+
+// btSquare squares lanes of level k as
+// (a_0 + a_1 X)^2 = a_0^2 + a_1^2 + a_1^2 X' X
+func btSquare(k int, a uint64) uint64 {
+	if k == 0 {
+		return a
+	}
+	a0, a1 := btSplit(k, a)
+	s0, s1 := btSquare(k-1, a0), btSquare(k-1, a1)
+	return btJoin(k, s0^s1, btMulX(k-1, s1))
+}
+
+// btInverse inverts lanes of level k with the conjugate a_0 + a_1 X' + a_1 X
+// and the norm a_0^2 + a_0 a_1 X' + a_1^2 one level below, since roots of
+// X^2 + X' X + 1 sum to X' and multiply to one. Inverse of zero is zero.
+func btInverse(k int, a uint64) uint64 {
+	if k == 0 {
+		return a
+	}
+	a0, a1 := btSplit(k, a)
+	n := btSquare(k-1, a0) ^ btMulX(k-1, btMul(k-1, a0, a1)) ^ btSquare(k-1, a1)
+	n = btInverse(k-1, n)
+	return btJoin(k, btMul(k-1, a0^btMulX(k-1, a1), n), btMul(k-1, a1, n))
+}
+
+// btBroadcast repeats an element of level k in all lanes.
+func btBroadcast(k int, a uint64) uint64 {
+	for w := 1 << k; w < 64; w *= 2 {
+		a |= a << w
+	}
+	return a
+}
+
+// btMulSubfield multiplies an element of level k by an element s of a lower
+// level j. τ_k is a vector space over τ_j whose coordinates are the lanes of
+// level j, so that it is a packed multiplication at level j.
+func btMulSubfield(j int, a, s uint64) uint64 {
+	return btMul(j, a, btBroadcast(j, s))
+}
+
+// btEmbed embeds an element of level j into a higher level, which keeps its bits.
+func btEmbed(j, k int, a uint64) (uint64, error) {
+	if j > k || k > btMaxLevel || j < 0 {
+		return 0, fmt.Errorf("can not embed level %d into level %d", j, k)
+	}
+	if j < btMaxLevel && a>>(1<<j) != 0 {
+		return 0, fmt.Errorf("%#x is not an element of level %d", a, j)
+	}
+	return a, nil
+}
+
+// btProject returns an element of level k as an element of lower level j
+// if it lies in the subfield.
+func btProject(k, j int, a uint64) (uint64, error) {
+	if j > k || k > btMaxLevel || j < 0 {
+		return 0, fmt.Errorf("can not project level %d onto level %d", k, j)
+	}
+	if j < btMaxLevel && a>>(1<<j) != 0 {
+		return 0, fmt.Errorf("%#x is not in subfield of level %d", a, j)
+	}
+	return a, nil
+}
+
+// btPack packs elements of level k into words, lane i of word w is element
+// w * 64 / 2^k + i.
+func btPack(k int, values []uint64) ([]uint64, error) {
+	w := 1 << k
+	lanes := 64 / w
+	packed := make([]uint64, (len(values)+lanes-1)/lanes)
+	for i, v := range values {
+		if w < 64 && v>>w != 0 {
+			return nil, fmt.Errorf("%#x is not an element of level %d", v, k)
+		}
+		packed[i/lanes] |= v << (w * (i % lanes))
+	}
+	return packed, nil
+}
+
+// btUnpack unpacks n elements of level k.
+func btUnpack(k int, packed []uint64, n int) []uint64 {
+	w := 1 << k
+	lanes := 64 / w
+	mask := uint64(1)<<w - 1
+	values := make([]uint64, n)
+	for i := range values {
+		values[i] = packed[i/lanes] >> (w * (i % lanes)) & mask
+	}
+	return values
+}
+
+// bt128 is an element of τ_7 = GF(2^128), a_0 + a_1 X_6 kept as {a_0, a_1}.
+type bt128 [2]uint64
+
+func mulBT128(a, b bt128) bt128 {
+	t0 := btMul(6, a[0], b[0])
+	t2 := btMul(6, a[1], b[1])
+	t1 := btMul(6, a[0]^a[1], b[0]^b[1])
+	return bt128{t0 ^ t2, t1 ^ t0 ^ t2 ^ btMulX(6, t2)}
+}
+
+func squareBT128(a bt128) bt128 {
+	s0, s1 := btSquare(6, a[0]), btSquare(6, a[1])
+	return bt128{s0 ^ s1, btMulX(6, s1)}
+}
+
+func inverseBT128(a bt128) bt128 {
+	n := btSquare(6, a[0]) ^ btMulX(6, btMul(6, a[0], a[1])) ^ btSquare(6, a[1])
+	n = btInverse(6, n)
+	return bt128{btMul(6, a[0]^btMulX(6, a[1]), n), btMul(6, a[1], n)}
+}
+
+// mulBT128Subfield multiplies by an element s of level j <= 6.
+func mulBT128Subfield(j int, a bt128, s uint64) bt128 {
+	b := btBroadcast(j, s)
+	return bt128{btMul(j, a[0], b), btMul(j, a[1], b)}
+}
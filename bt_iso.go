@@ -0,0 +1,128 @@
+package gf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Level six of the binary tower and GF(2^64) in polynomial basis under
+// x^64 + x^4 + x^3 + x + 1 are related by a GF(2) linear field isomorphism.
+// It is found as in tower extension of GF(2^64): X_5, which generates τ_6
+// over GF(2), is mapped to a root in GF(2^64) of its minimal polynomial.
+// Subfield τ_k is mapped onto the unique subfield GF(2^(2^k)) of GF(2^64),
+// so that small field data can be lifted to GF(2^64), encoded with transforms
+// whose twiddles are in GF(2^64), and be lowered back once decoded.
+
+// byteMap is a GF(2) linear map of 64 bit words applied with a table per byte.
+type byteMap [8][256]uint64
+
+func newByteMap(images [64]uint64) *byteMap {
+	m := &byteMap{}
+	for b := 0; b < 8; b++ {
+		for v := 1; v < 256; v++ {
+			low := v & -v
+			i := 0
+			for low>>i != 1 {
+				i++
+			}
+			m[b][v] = m[b][v^low] ^ images[8*b+i]
+		}
+	}
+	return m
+}
+
+func (m *byteMap) apply(a uint64) uint64 {
+	var r uint64
+	for b := 0; a != 0; b, a = b+1, a>>8 {
+		r ^= m[b][a&0xff]
+	}
+	return r
+}
+
+var (
+	btIsoOnce          sync.Once
+	btToPoly, polyToBT *byteMap
+	btIsoErr           error
+)
+
+func initBTIso() {
+	phi, err := embedGF64([2]uint64{1, 0}, func(a, b [2]uint64) [2]uint64 {
+		return [2]uint64{btMul(btMaxLevel, a[0], b[0]), 0}
+	}, func(c int) [2]uint64 {
+		return [2]uint64{1 << (c & 63), 0}
+	})
+	if err != nil {
+		btIsoErr = err
+		return
+	}
+	s := &gf2Span{}
+	var images [64]uint64
+	for j := 0; j < 64; j++ {
+		images[j] = phi[j][0]
+		s.insert(phi[j])
+	}
+	if s.rank() != 64 {
+		btIsoErr = errors.New("tower map is not bijective")
+		return
+	}
+	var inverses [64]uint64
+	for i := 0; i < 64; i++ {
+		comb, _ := s.solve([2]uint64{1 << i, 0})
+		inverses[i] = comb[0]
+	}
+	polyToBT, btToPoly = newByteMap(images), newByteMap(inverses)
+}
+
+func btIso() {
+	btIsoOnce.Do(initBTIso)
+	if btIsoErr != nil {
+		panic(btIsoErr)
+	}
+}
+
+// btToGF64 maps an element of level six to GF(2^64) in polynomial basis.
+func btToGF64(a uint64) uint64 {
+	btIso()
+	return btToPoly.apply(a)
+}
+
+// gf64ToBT maps an element of GF(2^64) in polynomial basis to level six.
+func gf64ToBT(a uint64) uint64 {
+	btIso()
+	return polyToBT.apply(a)
+}
+
+// btLift maps elements of level k to GF(2^64) in polynomial basis.
+func btLift(k int, values []uint64) ([]uint64, error) {
+	if k < 0 || k > btMaxLevel {
+		return nil, fmt.Errorf("tower level is expected to be in [0, %d]: %d", btMaxLevel, k)
+	}
+	btIso()
+	out := make([]uint64, len(values))
+	for i, v := range values {
+		if k < btMaxLevel && v>>(1<<k) != 0 {
+			return nil, fmt.Errorf("%#x is not an element of level %d", v, k)
+		}
+		out[i] = btToPoly.apply(v)
+	}
+	return out, nil
+}
+
+// btLower maps elements of GF(2^64) in polynomial basis to level k and fails
+// if an element is not in the subfield.
+func btLower(k int, values []uint64) ([]uint64, error) {
+	if k < 0 || k > btMaxLevel {
+		return nil, fmt.Errorf("tower level is expected to be in [0, %d]: %d", btMaxLevel, k)
+	}
+	btIso()
+	out := make([]uint64, len(values))
+	for i, v := range values {
+		a, err := btProject(btMaxLevel, k, polyToBT.apply(v))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = a
+	}
+	return out, nil
+}
@@ -0,0 +1,244 @@
+package gf
+
+import (
+	"testing"
+)
+
+func randBT(k int) uint64 {
+	if k == btMaxLevel {
+		return randGF64()
+	}
+	return randGF64() & (1<<(1<<k) - 1)
+}
+
+func TestBTFieldAxioms(t *testing.T) {
+	for k := 0; k <= btMaxLevel; k++ {
+		for i := 0; i < 200; i++ {
+			a, b, c := randBT(k), randBT(k), randBT(k)
+			if btMul(k, a, 1) != a || btMul(k, a, 0) != 0 {
+				t.Fatal("a * 1 == a, a * 0 == 0", k)
+			}
+			if btMul(k, a, b) != btMul(k, b, a) {
+				t.Fatal("a * b == b * a", k)
+			}
+			if btMul(k, btMul(k, a, b), c) != btMul(k, btMul(k, a, c), b) {
+				t.Fatal("(a * b) * c == (a * c) * b", k)
+			}
+			if btMul(k, a, b^c) != btMul(k, a, b)^btMul(k, a, c) {
+				t.Fatal("a * (b + c) == a * b + a * c", k)
+			}
+			if btSquare(k, a) != btMul(k, a, a) {
+				t.Fatal("a^2 == a * a", k)
+			}
+			if a != 0 && btMul(k, a, btInverse(k, a)) != 1 {
+				t.Fatal("a * a ^ -1 == 1", k)
+			}
+		}
+	}
+}
+
+func TestBTGenerators(t *testing.T) {
+	// X_0^2 + X_0 + 1 = 0 and X_k^2 + X_(k-1) X_k + 1 = 0
+	prev := uint64(1)
+	for k := 0; k < btMaxLevel; k++ {
+		x := uint64(1) << (1 << k)
+		if btSquare(k+1, x)^btMul(k+1, prev, x)^1 != 0 {
+			t.Fatal("bad defining polynomial at level", k+1)
+		}
+		if btMulX(k+1, 1) != x {
+			t.Fatal("bad generator at level", k+1)
+		}
+		prev = x
+	}
+	x6 := bt128{0, 1}
+	s := squareBT128(x6)
+	if s != (bt128{1, 1 << 32}) {
+		t.Fatal("X_6^2 == X_5 X_6 + 1")
+	}
+}
+
+func TestBTSubfields(t *testing.T) {
+	for j := 0; j <= btMaxLevel; j++ {
+		for k := j; k <= btMaxLevel; k++ {
+			a, b := randBT(j), randBT(j)
+			ea, err := btEmbed(j, k, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if btMul(k, ea, b) != btMul(j, a, b) {
+				t.Fatal("subfield multiplication must be kept", j, k)
+			}
+			if btInverse(k, ea) != btInverse(j, a) {
+				t.Fatal("subfield inverse must be kept", j, k)
+			}
+			if p, err := btProject(k, j, ea); err != nil || p != a {
+				t.Fatal("projection must revert embedding", j, k)
+			}
+			c := randBT(k)
+			if btMulSubfield(j, c, a) != btMul(k, c, a) {
+				t.Fatal("multiplication by subfield element", j, k)
+			}
+		}
+	}
+	if _, err := btProject(6, 3, 1<<8); err == nil {
+		t.Fatal("element out of subfield must not be projected")
+	}
+	if _, err := btEmbed(3, 2, 1); err == nil {
+		t.Fatal("embedding into lower level must fail")
+	}
+}
+
+func TestBTPacked(t *testing.T) {
+	for k := 0; k < btMaxLevel; k++ {
+		n := 3 * 64 >> k
+		a, b := make([]uint64, n), make([]uint64, n)
+		for i := range a {
+			a[i], b[i] = randBT(k), randBT(k)
+		}
+		pa, err := btPack(k, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pb, _ := btPack(k, b)
+		prod, inv := make([]uint64, len(pa)), make([]uint64, len(pa))
+		for w := range pa {
+			prod[w] = btMul(k, pa[w], pb[w])
+			inv[w] = btInverse(k, pa[w])
+		}
+		prodValues, invValues := btUnpack(k, prod, n), btUnpack(k, inv, n)
+		for i := range a {
+			if prodValues[i] != btMul(k, a[i], b[i]) || invValues[i] != btInverse(k, a[i]) {
+				t.Fatal("packed arithmetic must be lane by lane", k, i)
+			}
+		}
+		if values := btUnpack(k, pa, n); values[n-1] != a[n-1] {
+			t.Fatal("unpacking must revert packing", k)
+		}
+	}
+	if _, err := btPack(3, []uint64{1 << 8}); err == nil {
+		t.Fatal("element out of level must not be packed")
+	}
+}
+
+func TestBT128(t *testing.T) {
+	one := bt128{1, 0}
+	for i := 0; i < 200; i++ {
+		a := bt128{randGF64(), randGF64()}
+		b := bt128{randGF64(), randGF64()}
+		c := bt128{randGF64(), randGF64()}
+		if mulBT128(a, one) != a {
+			t.Fatal("a * 1 == a")
+		}
+		if mulBT128(mulBT128(a, b), c) != mulBT128(mulBT128(a, c), b) {
+			t.Fatal("(a * b) * c == (a * c) * b")
+		}
+		if squareBT128(a) != mulBT128(a, a) {
+			t.Fatal("a^2 == a * a")
+		}
+		if mulBT128(a, inverseBT128(a)) != one {
+			t.Fatal("a * a ^ -1 == 1")
+		}
+		s := randBT(3)
+		if mulBT128Subfield(3, a, s) != mulBT128(a, bt128{s, 0}) {
+			t.Fatal("multiplication by subfield element")
+		}
+	}
+}
+
+func TestBTIsomorphism(t *testing.T) {
+	if btToGF64(1) != 1 {
+		t.Fatal("one must be mapped to one")
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := randGF64(), randGF64()
+		pa, pb := btToGF64(a), btToGF64(b)
+		if btToGF64(btMul(btMaxLevel, a, b)) != mul64(pa, pb) {
+			t.Fatal("multiplication must be preserved")
+		}
+		if btToGF64(a^b) != pa^pb {
+			t.Fatal("addition must be preserved")
+		}
+		if gf64ToBT(pa) != a {
+			t.Fatal("maps must be inverses")
+		}
+	}
+	// subfield of level k is mapped onto the subfield GF(2^(2^k))
+	for k := 0; k < btMaxLevel; k++ {
+		lifted, err := btLift(k, []uint64{randBT(k)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := lifted[0]
+		for i := 0; i < 1<<k; i++ {
+			x = square64(x)
+		}
+		if x != lifted[0] {
+			t.Fatal("lifted element must be in the subfield", k)
+		}
+		lowered, err := btLower(k, lifted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := btLift(k, lowered); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := btLower(3, []uint64{defaultBasisGenerator}); err == nil {
+		t.Fatal("element out of subfield must not be lowered")
+	}
+}
+
+func TestCodecTower(t *testing.T) {
+	c, err := NewCodec(16, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]uint64, 10)
+	for i := range data {
+		data[i] = randBT(3)
+	}
+	codeword, err := c.EncodeTower(data, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := []int{0, 3, 5, 8, 13, 15}
+	for _, i := range missing {
+		codeword[i] = 0
+	}
+	decoded, err := c.DecodeTower(codeword, missing, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		if decoded[i] != data[i] {
+			t.Fatal("bytes must be decoded", i)
+		}
+	}
+	if _, err := c.EncodeTower([]uint64{1 << 8}, 3); err == nil {
+		t.Fatal("element out of level must be rejected")
+	}
+}
+
+func BenchmarkBTMulPacked8(t *testing.B) {
+	r0, r1 := randGF64(), randGF64()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		r0 = btMul(3, r0, r1)
+	}
+}
+
+func BenchmarkBTMul64(t *testing.B) {
+	r0, r1 := randGF64(), randGF64()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		r0 = btMul(6, r0, r1)
+	}
+}
+
+func BenchmarkBTMul64BySubfield8(t *testing.B) {
+	r0, s := randGF64(), randBT(3)
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		r0 = btMulSubfield(3, r0, s)
+	}
+}
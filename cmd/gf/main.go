@@ -0,0 +1,392 @@
+// Command gf encodes files into Reed-Solomon shard files and
+// decodes, verifies and repairs them.
+//
+//	gf encode [-n 16] [-k 10] [-stripe 65536] FILE   writes FILE.<i>.shard
+//	gf decode [-o OUT] [-f] FILE                     rebuilds FILE from its shards
+//	gf verify FILE                                   checks shards of FILE
+//	gf repair FILE                                   regenerates damaged shards
+//
+// Exit code is 0 on success, 1 on failure or when a stripe is unrecoverable,
+// 2 on bad usage and 3 when verify finds damaged but recoverable shards.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kilic/gf"
+)
+
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+	exitDamaged
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	usage := func() {
+		fmt.Fprintln(stderr, "usage: gf encode|decode|verify|repair [flags] FILE")
+	}
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	c := &command{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("gf "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&c.quiet, "q", false, "do not print progress")
+	var do func(file string) (int, error)
+	switch args[0] {
+	case "encode":
+		fs.IntVar(&c.n, "n", 16, "number of shards, a power of two")
+		fs.IntVar(&c.k, "k", 10, "number of shards enough to decode")
+		fs.IntVar(&c.stripeSize, "stripe", 1<<16, "stripe size in bytes, a multiple of 8")
+		do = c.encode
+	case "decode":
+		fs.StringVar(&c.out, "o", "", "output file, FILE if empty")
+		fs.BoolVar(&c.force, "f", false, "overwrite output file")
+		do = c.decode
+	case "verify":
+		do = c.verify
+	case "repair":
+		do = c.repair
+	default:
+		usage()
+		return exitUsage
+	}
+	file, err := parseArgs(fs, args[1:])
+	if err != nil {
+		if err != errUsage && err != flag.ErrHelp {
+			fmt.Fprintln(stderr, err)
+		}
+		usage()
+		return exitUsage
+	}
+	code, err := do(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "gf %s: %v\n", args[0], err)
+		if code == exitOK {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// parseArgs parses flags given before or after the single file argument.
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", errUsage
+	}
+	file := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 0 {
+		return "", errUsage
+	}
+	return file, nil
+}
+
+type command struct {
+	stdout, stderr io.Writer
+	quiet          bool
+	n, k           int
+	stripeSize     int
+	out            string
+	force          bool
+}
+
+func shardPath(file string, i int) string {
+	return fmt.Sprintf("%s.%d.shard", file, i)
+}
+
+func (c *command) progress(action, unit string) func(done, total int64) {
+	if c.quiet {
+		return nil
+	}
+	return func(done, total int64) {
+		fmt.Fprintf(c.stderr, "\r%s: %d/%d %s", action, done, total, unit)
+		if done == total {
+			fmt.Fprintln(c.stderr)
+		}
+	}
+}
+
+func (c *command) encode(file string) (int, error) {
+	codec, err := gf.NewCodec(c.n, c.k)
+	if err != nil {
+		return exitUsage, err
+	}
+	e, err := gf.NewStreamEncoder(codec, c.stripeSize)
+	if err != nil {
+		return exitUsage, err
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return exitFailure, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return exitFailure, err
+	}
+	files := make([]*os.File, c.n)
+	ws := make([]io.Writer, c.n)
+	for i := range files {
+		if files[i], err = os.Create(shardPath(file, i)); err != nil {
+			return exitFailure, err
+		}
+		defer files[i].Close()
+		ws[i] = files[i]
+	}
+	var r io.Reader = in
+	if !c.quiet {
+		r = &progressReader{r: in, total: info.Size(), report: c.progress("encode", "bytes")}
+	}
+	if err := e.EncodeShards(r, info.Size(), ws); err != nil {
+		return exitFailure, err
+	}
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			return exitFailure, err
+		}
+	}
+	fmt.Fprintf(c.stdout, "encoded %s into %d shards, any %d of them are enough to decode\n", file, c.n, c.k)
+	return exitOK, nil
+}
+
+// progressReader reports progress of encoding in bytes of the input.
+type progressReader struct {
+	r           io.Reader
+	read, total int64
+	report      func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	l, err := p.r.Read(b)
+	p.read += int64(l)
+	if l > 0 || p.total == 0 {
+		p.report(p.read, p.total)
+	}
+	return l, err
+}
+
+// shardFiles lists shard files of FILE keyed by shard index in their headers.
+// Files with unreadable headers are listed as bad.
+func shardFiles(file string) (map[int]string, []string, error) {
+	paths, err := filepath.Glob(file + ".*.shard")
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(paths)
+	files := map[int]string{}
+	bad := []string{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			bad = append(bad, path)
+			continue
+		}
+		r, err := gf.NewShardReader(f)
+		f.Close()
+		if err != nil {
+			bad = append(bad, path)
+			continue
+		}
+		i := r.Header().Index
+		if other, ok := files[i]; ok {
+			return nil, nil, fmt.Errorf("%s and %s both hold shard %d", other, path, i)
+		}
+		files[i] = path
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no readable shard files of %s", file)
+	}
+	return files, bad, nil
+}
+
+// openShards opens shard files of FILE. Returned function closes them.
+func (c *command) openShards(file string, action string) (*gf.ShardSet, map[int]string, func(), error) {
+	paths, bad, err := shardFiles(file)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, path := range bad {
+		fmt.Fprintf(c.stderr, "skipping %s: unreadable header\n", path)
+	}
+	files := []*os.File{}
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	rs := []io.Reader{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "skipping %s: %v\n", path, err)
+			continue
+		}
+		files = append(files, f)
+		rs = append(rs, f)
+	}
+	s, err := gf.OpenShards(rs)
+	if err != nil {
+		closeAll()
+		return nil, nil, nil, err
+	}
+	s.Progress = c.progress(action, "stripes")
+	return s, paths, closeAll, nil
+}
+
+func (c *command) decode(file string) (int, error) {
+	s, _, closeShards, err := c.openShards(file, "decode")
+	if err != nil {
+		return exitFailure, err
+	}
+	defer closeShards()
+	out := c.out
+	if out == "" {
+		out = file
+	}
+	if _, err := os.Stat(out); err == nil && !c.force {
+		return exitFailure, fmt.Errorf("%s exists", out)
+	}
+	// decoded file is written aside so that a failure leaves no partial output
+	f, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".*.tmp")
+	if err != nil {
+		return exitFailure, err
+	}
+	defer os.Remove(f.Name())
+	if err := s.Decode(f); err != nil {
+		f.Close()
+		return exitFailure, err
+	}
+	if err := f.Close(); err != nil {
+		return exitFailure, err
+	}
+	if err := os.Rename(f.Name(), out); err != nil {
+		return exitFailure, err
+	}
+	fmt.Fprintf(c.stdout, "decoded %s\n", out)
+	return exitOK, nil
+}
+
+func (c *command) verifyShards(file string) (*gf.ShardReport, map[int]string, error) {
+	s, paths, closeShards, err := c.openShards(file, "verify")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeShards()
+	r, err := s.Verify()
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, paths, nil
+}
+
+func (c *command) report(r *gf.ShardReport) {
+	fmt.Fprintf(c.stdout, "stripes: %d\n", r.Stripes)
+	if len(r.Damaged) != 0 {
+		fmt.Fprintf(c.stdout, "damaged shards: %v\n", r.Damaged)
+	}
+	if len(r.Unrecoverable) != 0 {
+		fmt.Fprintf(c.stdout, "unrecoverable stripes: %v\n", r.Unrecoverable)
+	}
+	if len(r.Inconsistent) != 0 {
+		fmt.Fprintf(c.stdout, "inconsistent stripes: %v\n", r.Inconsistent)
+	}
+}
+
+func (c *command) verify(file string) (int, error) {
+	r, _, err := c.verifyShards(file)
+	if err != nil {
+		return exitFailure, err
+	}
+	c.report(r)
+	switch {
+	case !r.Recoverable():
+		return exitFailure, errors.New("shards are not recoverable")
+	case !r.Healthy():
+		fmt.Fprintln(c.stdout, "shards are damaged but recoverable")
+		return exitDamaged, nil
+	}
+	fmt.Fprintln(c.stdout, "shards are healthy")
+	return exitOK, nil
+}
+
+func (c *command) repair(file string) (int, error) {
+	r, paths, err := c.verifyShards(file)
+	if err != nil {
+		return exitFailure, err
+	}
+	c.report(r)
+	if !r.Recoverable() {
+		return exitFailure, errors.New("shards are not recoverable")
+	}
+	if r.Healthy() {
+		fmt.Fprintln(c.stdout, "shards are healthy")
+		return exitOK, nil
+	}
+
+	s, _, closeShards, err := c.openShards(file, "repair")
+	if err != nil {
+		return exitFailure, err
+	}
+	defer closeShards()
+	// repaired shards are written aside and moved over damaged ones at the end
+	tmp := map[int]*os.File{}
+	defer func() {
+		for _, f := range tmp {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	ws := map[int]io.Writer{}
+	for _, i := range r.Damaged {
+		path, ok := paths[i]
+		if !ok {
+			path = shardPath(file, i)
+		}
+		f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return exitFailure, err
+		}
+		tmp[i] = f
+		ws[i] = f
+	}
+	if err := s.Repair(ws); err != nil {
+		return exitFailure, err
+	}
+	for _, i := range r.Damaged {
+		f := tmp[i]
+		if err := f.Close(); err != nil {
+			return exitFailure, err
+		}
+		path, ok := paths[i]
+		if !ok {
+			path = shardPath(file, i)
+		}
+		if err := os.Rename(f.Name(), path); err != nil {
+			return exitFailure, err
+		}
+		delete(tmp, i)
+		fmt.Fprintf(c.stdout, "repaired %s\n", path)
+	}
+	return exitOK, nil
+}
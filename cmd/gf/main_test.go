@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runGF(t *testing.T, args ...string) int {
+	return run(append(args, "-q"), io.Discard, io.Discard)
+}
+
+func TestEncodeDecodeRepair(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "object")
+	input := make([]byte, 10000)
+	_, _ = rand.Read(input)
+	if err := os.WriteFile(file, input, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runGF(t, "encode", file, "-n", "8", "-k", "5", "-stripe", "256"); code != exitOK {
+		t.Fatal("encode failed", code)
+	}
+	if code := runGF(t, "verify", file); code != exitOK {
+		t.Fatal("fresh shards must verify", code)
+	}
+
+	// lose one shard and corrupt another
+	if err := os.Remove(shardPath(file, 2)); err != nil {
+		t.Fatal(err)
+	}
+	shard, err := os.ReadFile(shardPath(file, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := append([]byte{}, shard...)
+	shard[len(shard)/2] ^= 1
+	if err := os.WriteFile(shardPath(file, 6), shard, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runGF(t, "verify", file); code != exitDamaged {
+		t.Fatal("damaged shards must be reported", code)
+	}
+
+	out := filepath.Join(dir, "decoded")
+	if code := runGF(t, "decode", file, "-o", out); code != exitOK {
+		t.Fatal("decode failed", code)
+	}
+	decoded, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, input) {
+		t.Fatal("decoded file differs")
+	}
+	if code := runGF(t, "decode", file, "-o", out); code != exitFailure {
+		t.Fatal("existing output must not be overwritten", code)
+	}
+
+	if code := runGF(t, "repair", file); code != exitOK {
+		t.Fatal("repair failed", code)
+	}
+	if code := runGF(t, "verify", file); code != exitOK {
+		t.Fatal("repaired shards must verify", code)
+	}
+	repaired, err := os.ReadFile(shardPath(file, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(repaired, original) {
+		t.Fatal("repaired shard differs")
+	}
+
+	// four of eight shards are lost
+	for _, i := range []int{0, 1, 3, 7} {
+		if err := os.Remove(shardPath(file, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if code := runGF(t, "verify", file); code != exitFailure {
+		t.Fatal("unrecoverable shards must fail", code)
+	}
+	if code := runGF(t, "decode", file, "-o", out, "-f"); code != exitFailure {
+		t.Fatal("decode must fail", code)
+	}
+}
+
+func TestDuplicateShardIndex(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "object")
+	if err := os.WriteFile(file, []byte("duplicate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runGF(t, "encode", file, "-n", "4", "-k", "2"); code != exitOK {
+		t.Fatal("encode failed", code)
+	}
+	shard, err := os.ReadFile(shardPath(file, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := file + ".copy.shard"
+	if err := os.WriteFile(copied, shard, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = shardFiles(file)
+	if err == nil {
+		t.Fatal("shard files holding the same index must be rejected")
+	}
+	if !strings.Contains(err.Error(), shardPath(file, 1)) || !strings.Contains(err.Error(), copied) {
+		t.Fatal("error must name both files", err)
+	}
+	if code := runGF(t, "verify", file); code != exitFailure {
+		t.Fatal("verify must fail", code)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"unknown", "x"}, {"encode"}, {"verify", "a", "b"}, {"encode", "-n"}} {
+		if code := run(args, io.Discard, io.Discard); code != exitUsage {
+			t.Fatal("usage error expected", args, code)
+		}
+	}
+}
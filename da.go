@@ -0,0 +1,231 @@
+package gf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A data square of k x k symbols is extended to 2k x 2k as in data availability
+// sampling. Rows of data are extended with systematic Reed-Solomon encoding,
+// that is a row holds evaluations of a polynomial of degree less than k at the
+// first k points of the domain and extension is its evaluations at the
+// next k points. Then all 2k columns are extended in the same way, so that
+// every row and column of the extended square is a codeword of length 2k.
+// Quadrants are the original data, row extension, column extension and
+// extension of both.
+
+// ErrUnrecoverableSquare is returned when iterative repair of an extended
+// square stops with missing cells.
+var ErrUnrecoverableSquare = errors.New("square is not recoverable")
+
+// extendSystematic extends evaluations over the first k points with
+// evaluations over the next k points, with an inverse transform of size k
+// and a transform of size 2k. A single value is a constant polynomial
+// and is repeated, transforms of size one being undefined.
+func extendSystematic(values []uint64) ([]uint64, error) {
+	k := len(values)
+	if k == 1 {
+		return []uint64{values[0], values[0]}, nil
+	}
+	p := newEmptyPoly(k)
+	copy(p.a, values)
+	if _, err := p.lifft(); err != nil {
+		return nil, err
+	}
+	p.expand(2 * k)
+	if _, err := p.lfft(); err != nil {
+		return nil, err
+	}
+	return p.a, nil
+}
+
+// ExtendSquare extends a k x k data square to 2k x 2k where k is a power of two.
+func ExtendSquare(data [][]uint64) ([][]uint64, error) {
+	k := len(data)
+	if k == 0 || k&(k-1) != 0 {
+		return nil, fmt.Errorf("square width is expected to be a power of two: %d", k)
+	}
+	ensureDefaultBasis(log2Floor(2 * k))
+	square := make([][]uint64, 2*k)
+	for i := 0; i < k; i++ {
+		if len(data[i]) != k {
+			return nil, fmt.Errorf("row %d length %d is expected to be %d", i, len(data[i]), k)
+		}
+		row, err := extendSystematic(data[i])
+		if err != nil {
+			return nil, err
+		}
+		square[i] = row
+	}
+	for i := k; i < 2*k; i++ {
+		square[i] = make([]uint64, 2*k)
+	}
+	col := make([]uint64, k)
+	for j := 0; j < 2*k; j++ {
+		for i := 0; i < k; i++ {
+			col[i] = square[i][j]
+		}
+		ext, err := extendSystematic(col)
+		if err != nil {
+			return nil, err
+		}
+		for i := k; i < 2*k; i++ {
+			square[i][j] = ext[i]
+		}
+	}
+	return square, nil
+}
+
+// squareLine is a row or a column of an extended square.
+type squareLine struct {
+	row   bool
+	index int
+}
+
+func (l squareLine) String() string {
+	if l.row {
+		return fmt.Sprintf("row %d", l.index)
+	}
+	return fmt.Sprintf("column %d", l.index)
+}
+
+// cell returns coordinates of t-th cell of the line.
+func (l squareLine) cell(t int) (int, int) {
+	if l.row {
+		return l.index, t
+	}
+	return t, l.index
+}
+
+// repairSquare runs iterative repair over known mask. A line with at least
+// k known cells is completed with fn, or only in mask if fn is nil, and
+// lines are repeatedly visited until no line is completed. It returns
+// number of cells left unknown.
+func repairSquare(known [][]bool, fn func(l squareLine, missing []int) error) (int, error) {
+	w := len(known)
+	k := w / 2
+	for progress := true; progress; {
+		progress = false
+		for _, row := range []bool{true, false} {
+			for index := 0; index < w; index++ {
+				l := squareLine{row, index}
+				missing := []int{}
+				for t := 0; t < w; t++ {
+					if i, j := l.cell(t); !known[i][j] {
+						missing = append(missing, t)
+					}
+				}
+				if len(missing) == 0 || len(missing) > k {
+					continue
+				}
+				if fn != nil {
+					if err := fn(l, missing); err != nil {
+						return 0, err
+					}
+				}
+				for _, t := range missing {
+					i, j := l.cell(t)
+					known[i][j] = true
+				}
+				progress = true
+			}
+		}
+	}
+	left := 0
+	for i := 0; i < w; i++ {
+		for j := 0; j < w; j++ {
+			if !known[i][j] {
+				left++
+			}
+		}
+	}
+	return left, nil
+}
+
+func checkSquareMask(known [][]bool) error {
+	w := len(known)
+	if w < 2 || w&(w-1) != 0 {
+		return fmt.Errorf("square width is expected to be a power of two: %d", w)
+	}
+	for i := range known {
+		if len(known[i]) != w {
+			return fmt.Errorf("row %d length %d is expected to be %d", i, len(known[i]), w)
+		}
+	}
+	return nil
+}
+
+// SquareRecoverable checks if iterative repair completes an extended square
+// from the cells in known mask, without touching cell values. Iterative
+// repair fails on stopping sets such as k + 1 rows and k + 1 columns
+// missing at their intersections.
+func SquareRecoverable(known [][]bool) (bool, error) {
+	if err := checkSquareMask(known); err != nil {
+		return false, err
+	}
+	mask := make([][]bool, len(known))
+	for i := range known {
+		mask[i] = append([]bool{}, known[i]...)
+	}
+	left, err := repairSquare(mask, nil)
+	if err != nil {
+		return false, err
+	}
+	return left == 0, nil
+}
+
+// RepairSquare fills unknown cells of an extended square in place with
+// iterative row and column erasure decoding. known is updated as cells
+// are recovered. Lines completed are checked to be codewords so that
+// a badly extended square is reported.
+func RepairSquare(square [][]uint64, known [][]bool) error {
+	if err := checkSquareMask(known); err != nil {
+		return err
+	}
+	w := len(known)
+	if len(square) != w {
+		return fmt.Errorf("square width %d is expected to be %d", len(square), w)
+	}
+	for i := range square {
+		if len(square[i]) != w {
+			return fmt.Errorf("row %d length %d is expected to be %d", i, len(square[i]), w)
+		}
+	}
+	c, err := NewCodec(w, w/2)
+	if err != nil {
+		return err
+	}
+	line := make([]uint64, w)
+	left, err := repairSquare(known, func(l squareLine, missing []int) error {
+		for t := 0; t < w; t++ {
+			i, j := l.cell(t)
+			line[t] = square[i][j]
+		}
+		plan, err := c.NewDecodePlan(missing)
+		if err != nil {
+			return err
+		}
+		if err := plan.Reconstruct(line); err != nil {
+			return err
+		}
+		ok, err := c.Verify(line)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s is not a codeword", l)
+		}
+		for _, t := range missing {
+			i, j := l.cell(t)
+			square[i][j] = line[t]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if left != 0 {
+		return fmt.Errorf("%w, %d cells are missing", ErrUnrecoverableSquare, left)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package gf
+
+import (
+	"testing"
+)
+
+func randSquare(k int) [][]uint64 {
+	data := make([][]uint64, k)
+	for i := range data {
+		data[i] = randPoly(k).a
+	}
+	return data
+}
+
+func squareMask(w int, known bool) [][]bool {
+	mask := make([][]bool, w)
+	for i := range mask {
+		mask[i] = make([]bool, w)
+		for j := range mask[i] {
+			mask[i][j] = known
+		}
+	}
+	return mask
+}
+
+func TestExtendSquare(t *testing.T) {
+	k := 8
+	data := randSquare(k)
+	square, err := ExtendSquare(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCodec(2*k, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			if square[i][j] != data[i][j] {
+				t.Fatal("data must be kept in first quadrant")
+			}
+		}
+	}
+	col := make([]uint64, 2*k)
+	for i := 0; i < 2*k; i++ {
+		for t0 := 0; t0 < 2*k; t0++ {
+			col[t0] = square[t0][i]
+		}
+		for _, line := range [][]uint64{square[i], col} {
+			ok, err := c.Verify(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatal("rows and columns must be codewords", i)
+			}
+		}
+	}
+}
+
+func TestExtendSquareSingle(t *testing.T) {
+	square, err := ExtendSquare([][]uint64{{7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(square) != 2 {
+		t.Fatal("square must be extended to width two", square)
+	}
+	for i := range square {
+		if len(square[i]) != 2 {
+			t.Fatal("square must be extended to width two", square)
+		}
+		for j := range square[i] {
+			if square[i][j] != 7 {
+				t.Fatal("single symbol must be repeated", square)
+			}
+		}
+	}
+}
+
+func TestRepairSquare(t *testing.T) {
+	k := 8
+	square, err := ExtendSquare(randSquare(k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for trial := 0; trial < 10; trial++ {
+		known := squareMask(2*k, false)
+		partial := make([][]uint64, 2*k)
+		for i := range partial {
+			partial[i] = make([]uint64, 2*k)
+			for j := range partial[i] {
+				// about 45% of cells are sampled
+				if randGF64()%100 < 45 {
+					known[i][j] = true
+					partial[i][j] = square[i][j]
+				}
+			}
+		}
+		ok, err := SquareRecoverable(known)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = RepairSquare(partial, known)
+		if !ok {
+			if err == nil {
+				t.Fatal("unrecoverable pattern must fail")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range partial {
+			for j := range partial[i] {
+				if partial[i][j] != square[i][j] || !known[i][j] {
+					t.Fatal("bad repair", i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestRepairSquareStoppingSet(t *testing.T) {
+	k := 4
+	square, err := ExtendSquare(randSquare(k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (k + 1) x (k + 1) missing block can not be repaired iteratively
+	known := squareMask(2*k, true)
+	for i := 0; i <= k; i++ {
+		for j := 0; j <= k; j++ {
+			known[i][j] = false
+		}
+	}
+	ok, err := SquareRecoverable(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("stopping set must be detected")
+	}
+	if err := RepairSquare(square, known); err == nil {
+		t.Fatal("repair must fail on a stopping set")
+	}
+
+	// k x k missing block is repaired
+	known = squareMask(2*k, true)
+	partial := make([][]uint64, 2*k)
+	for i := range partial {
+		partial[i] = append([]uint64{}, square[i]...)
+	}
+	for i := k; i < 2*k; i++ {
+		for j := 0; j < k; j++ {
+			known[i][j] = false
+			partial[i][j] = 0
+		}
+	}
+	if err := RepairSquare(partial, known); err != nil {
+		t.Fatal(err)
+	}
+	for i := range partial {
+		if !newPoly(partial[i]).equalInCoeff(newPoly(square[i])) {
+			t.Fatal("bad repair", i)
+		}
+	}
+
+	// corrupt extension is reported
+	known[5][0] = false
+	partial[5][1] ^= 1
+	if err := RepairSquare(partial, known); err == nil {
+		t.Fatal("bad extension must be reported")
+	}
+}
@@ -20,3 +20,6 @@ func ibutterfly(k, k2 *uint64, G uint64)
 
 //go:noescape
 func lamire(a, b uint64) uint64
+
+//go:noescape
+func mul128(c, a, b *gf128)
@@ -0,0 +1,350 @@
+package gf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+)
+
+// FRI is a low degree test for evaluations over the span of default basis.
+//
+// With Cantor basis q(x) = x^2 + x maps span of m bases onto span of m - 1
+// bases, as q(combinations[i]) = combinations[i >> 1]. Any f with degree
+// less than 2d is uniquely f(x) = g(q(x)) + x h(q(x)) where g and h have
+// degree less than d, which is the Taylor expansion at x^2 + x that radix
+// conversion is built on. Points 2j and 2j + 1 differ by one and are
+// mapped to j, so that
+//
+//	h(y) = f(s) + f(s + 1), g(y) = f(s) + s h(y)
+//
+// where s = combinations[2j], y = combinations[j]. A round folds f into
+// g + α h with a random α and halves both the domain and the degree bound.
+//
+// Prover commits to each layer with a Merkle tree whose leaf j is the pair
+// of values at 2j and 2j + 1, and sends the last layer as coefficients
+// in novel polynomial basis. Challenges are derived with Fiat-Shamir
+// from a transcript over commitments.
+type FRI struct {
+	logBlowup   int
+	queries     int
+	finalDegree int
+	h           func() hash.Hash
+}
+
+// FRIConfig sets soundness parameters of FRI.
+type FRIConfig struct {
+	// LogBlowup is log of inverse of the rate, evaluation domain is
+	// 2^LogBlowup times the degree bound.
+	LogBlowup int
+	// Queries is number of queries.
+	Queries int
+	// FinalDegree is the degree bound of the last layer that is sent in
+	// clear, a power of two.
+	FinalDegree int
+	// Hash is the hash function of commitments and transcript, SHA-256 if nil.
+	Hash func() hash.Hash
+}
+
+// DefaultFRIConfig has rate 1/4 and 50 queries for about 100 bits of
+// conjectured security.
+var DefaultFRIConfig = FRIConfig{LogBlowup: 2, Queries: 50, FinalDegree: 1}
+
+// ErrHighDegree is returned when proven evaluations are not of a polynomial
+// below the degree bound.
+var ErrHighDegree = errors.New("evaluations are not of low degree")
+
+// NewFRI returns FRI prover and verifier with given parameters.
+func NewFRI(cfg FRIConfig) (*FRI, error) {
+	if cfg.LogBlowup < 1 || cfg.LogBlowup > 16 {
+		return nil, fmt.Errorf("log blowup is expected to be in [1, 16]: %d", cfg.LogBlowup)
+	}
+	if cfg.Queries < 1 {
+		return nil, fmt.Errorf("number of queries is expected to be positive: %d", cfg.Queries)
+	}
+	if cfg.FinalDegree < 1 || cfg.FinalDegree&(cfg.FinalDegree-1) != 0 {
+		return nil, fmt.Errorf("final degree is expected to be a power of two: %d", cfg.FinalDegree)
+	}
+	h := cfg.Hash
+	if h == nil {
+		h = sha256.New
+	}
+	if h().Size() < 8 {
+		return nil, errors.New("hash size is expected to be at least 8 bytes")
+	}
+	return &FRI{logBlowup: cfg.LogBlowup, queries: cfg.Queries, finalDegree: cfg.FinalDegree, h: h}, nil
+}
+
+// ConjecturedSecurity returns bits of security under the usual conjecture
+// that each query catches a far function with probability of 1 - rate.
+func (f *FRI) ConjecturedSecurity() int {
+	return f.queries * f.logBlowup
+}
+
+// FRIProof proves that committed evaluations are of a low degree polynomial.
+type FRIProof struct {
+	// Roots are commitments of folded layers, first one is commitment of
+	// the evaluations.
+	Roots [][]byte
+	// Final is the last layer in novel polynomial basis.
+	Final []uint64
+	// Layers are openings of queried pairs of each committed layer.
+	Layers []FRILayerOpening
+}
+
+// FRILayerOpening opens queried pairs of a layer, Pairs[t] being the pair
+// at Proof.Indices[t].
+type FRILayerOpening struct {
+	Pairs [][2]uint64
+	Proof *MerkleMultiproof
+}
+
+// Commitment returns commitment of the evaluations.
+func (p *FRIProof) Commitment() []byte {
+	return p.Roots[0]
+}
+
+// rounds returns number of folds for evaluations of length n.
+func (f *FRI) rounds(n int) (int, error) {
+	if n < 2 || n&(n-1) != 0 {
+		return 0, fmt.Errorf("number of evaluations is expected to be a power of two: %d", n)
+	}
+	if n > MaxShards {
+		return 0, fmt.Errorf("number of evaluations %d exceeds %d", n, MaxShards)
+	}
+	d := n >> f.logBlowup
+	if d <= f.finalDegree {
+		return 0, fmt.Errorf("degree bound %d is expected to be larger than final degree %d", d, f.finalDegree)
+	}
+	r := 0
+	for ; d > f.finalDegree; d >>= 1 {
+		r++
+	}
+	return r, nil
+}
+
+func pairLeaf(a, b uint64) []byte {
+	leaf := binary.LittleEndian.AppendUint64(nil, a)
+	return binary.LittleEndian.AppendUint64(leaf, b)
+}
+
+func (f *FRI) commit(layer []uint64) (*MerkleTree, error) {
+	leaves := make([][]byte, len(layer)/2)
+	for j := range leaves {
+		leaves[j] = pairLeaf(layer[2*j], layer[2*j+1])
+	}
+	return NewMerkleTree(leaves, f.h)
+}
+
+// fold folds pair of values at 2j and 2j + 1 into value at j of the next layer.
+func fold(j int, a, b, alpha uint64) uint64 {
+	h := a ^ b
+	return a ^ mul64(defaultBasis().combinations[2*j], h) ^ mul64(alpha, h)
+}
+
+// Prove proves that evaluations over first n combinations of default basis
+// are of a polynomial of degree less than n / 2^LogBlowup.
+func (f *FRI) Prove(evals []uint64) (*FRIProof, error) {
+	proof, high, err := f.prove(evals)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range high {
+		if c != 0 {
+			return nil, ErrHighDegree
+		}
+	}
+	return proof, nil
+}
+
+// prove builds a proof and also returns coefficients of the last layer
+// above its degree bound, which are dropped from the proof.
+func (f *FRI) prove(evals []uint64) (*FRIProof, []uint64, error) {
+	r, err := f.rounds(len(evals))
+	if err != nil {
+		return nil, nil, err
+	}
+	ensureDefaultBasis(log2Floor(len(evals)))
+	t := f.transcript(len(evals))
+	proof := &FRIProof{}
+	trees := make([]*MerkleTree, r)
+	layers := make([][]uint64, r)
+	layer := evals
+	for i := 0; i < r; i++ {
+		tree, err := f.commit(layer)
+		if err != nil {
+			return nil, nil, err
+		}
+		trees[i], layers[i] = tree, layer
+		proof.Roots = append(proof.Roots, tree.Root())
+		t.absorb(tree.Root())
+		alpha := t.challenge()
+		next := make([]uint64, len(layer)/2)
+		for j := range next {
+			next[j] = fold(j, layer[2*j], layer[2*j+1], alpha)
+		}
+		layer = next
+	}
+	final := newPoly(append([]uint64{}, layer...))
+	if _, err := final.lifft(); err != nil {
+		return nil, nil, err
+	}
+	d := len(layer) >> f.logBlowup
+	proof.Final = final.a[:d]
+	t.absorbSymbols(proof.Final)
+
+	queries := f.queryIndices(t, len(evals))
+	for i := 0; i < r; i++ {
+		mp, err := trees[i].ProveMulti(layerIndices(queries, i))
+		if err != nil {
+			return nil, nil, err
+		}
+		opening := FRILayerOpening{Proof: mp}
+		for _, j := range mp.Indices {
+			opening.Pairs = append(opening.Pairs, [2]uint64{layers[i][2*j], layers[i][2*j+1]})
+		}
+		proof.Layers = append(proof.Layers, opening)
+	}
+	return proof, final.a[d:], nil
+}
+
+// Verify checks that proof is valid for evaluations of length n and returns
+// the commitment of the evaluations.
+func (f *FRI) Verify(n int, proof *FRIProof) ([]byte, error) {
+	if proof == nil {
+		return nil, errors.New("proof is nil")
+	}
+	r, err := f.rounds(n)
+	if err != nil {
+		return nil, err
+	}
+	if len(proof.Roots) != r || len(proof.Layers) != r {
+		return nil, fmt.Errorf("expected %d layers", r)
+	}
+	d := (n >> r) >> f.logBlowup
+	if len(proof.Final) != d {
+		return nil, fmt.Errorf("expected %d final coefficients, got %d", d, len(proof.Final))
+	}
+	t := f.transcript(n)
+	alphas := make([]uint64, r)
+	for i := 0; i < r; i++ {
+		t.absorb(proof.Roots[i])
+		alphas[i] = t.challenge()
+	}
+	t.absorbSymbols(proof.Final)
+
+	queries := f.queryIndices(t, n)
+	// pairs[i] are opened pairs of layer i by pair index
+	pairs := make([]map[int][2]uint64, r)
+	for i, opening := range proof.Layers {
+		indices := layerIndices(queries, i)
+		mp := opening.Proof
+		if mp == nil || len(mp.Indices) != len(indices) || len(opening.Pairs) != len(indices) {
+			return nil, fmt.Errorf("bad opening of layer %d", i)
+		}
+		leaves := make([][]byte, len(indices))
+		pairs[i] = make(map[int][2]uint64, len(indices))
+		for e, j := range indices {
+			if mp.Indices[e] != j {
+				return nil, fmt.Errorf("bad opening of layer %d", i)
+			}
+			leaves[e] = pairLeaf(opening.Pairs[e][0], opening.Pairs[e][1])
+			pairs[i][j] = opening.Pairs[e]
+		}
+		if mp.Depth != log2Ceil(n>>(i+1)) || !VerifyMerkleMultiproof(proof.Roots[i], leaves, mp, f.h) {
+			return nil, fmt.Errorf("bad commitment opening of layer %d", i)
+		}
+	}
+
+	// default basis grows only for a proof of the expected shape
+	ensureDefaultBasis(log2Floor(n))
+	final := newEmptyPoly(n >> r)
+	copy(final.a, proof.Final)
+	if _, err := final.lfft(); err != nil {
+		return nil, err
+	}
+	for _, q := range queries {
+		// q is a pair index of the first layer and a value index of the second
+		for i := 0; i < r; i++ {
+			j := q >> i
+			pair := pairs[i][j]
+			v := fold(j, pair[0], pair[1], alphas[i])
+			var want uint64
+			if i+1 < r {
+				want = pairs[i+1][j>>1][j&1]
+			} else {
+				want = final.a[j]
+			}
+			if v != want {
+				return nil, fmt.Errorf("%w, folding of layer %d is inconsistent", ErrHighDegree, i)
+			}
+		}
+	}
+	return proof.Roots[0], nil
+}
+
+// transcript starts a transcript bound to parameters and length of evaluations.
+func (f *FRI) transcript(n int) *transcript {
+	t := newTranscript(f.h, "gf fri")
+	t.absorbSymbols([]uint64{uint64(f.logBlowup), uint64(f.queries), uint64(f.finalDegree), uint64(n)})
+	return t
+}
+
+// queryIndices draws pair indices of the first layer of evaluations of length n.
+func (f *FRI) queryIndices(t *transcript, n int) []int {
+	queries := make([]int, f.queries)
+	for e := range queries {
+		queries[e] = int(t.challenge() & uint64(n/2-1))
+	}
+	return queries
+}
+
+// layerIndices returns sorted distinct pair indices of layer i opened by queries.
+// Queries are in range so that sorting can not fail.
+func layerIndices(queries []int, i int) []int {
+	indices := make([]int, len(queries))
+	for e, q := range queries {
+		indices[e] = q >> i
+	}
+	indices, _ = sortedIndices(indices, math.MaxInt)
+	return indices
+}
+
+// transcript is a Fiat-Shamir transcript that chains hashes of absorbed
+// messages and of challenges drawn.
+type transcript struct {
+	h     func() hash.Hash
+	state []byte
+}
+
+func newTranscript(h func() hash.Hash, label string) *transcript {
+	t := &transcript{h: h}
+	t.absorb([]byte(label))
+	return t
+}
+
+func (t *transcript) absorb(data []byte) {
+	d := t.h()
+	d.Write([]byte{0})
+	d.Write(t.state)
+	d.Write(data)
+	t.state = d.Sum(nil)
+}
+
+func (t *transcript) absorbSymbols(symbols []uint64) {
+	data := make([]byte, 0, 8*len(symbols))
+	for _, v := range symbols {
+		data = binary.LittleEndian.AppendUint64(data, v)
+	}
+	t.absorb(data)
+}
+
+func (t *transcript) challenge() uint64 {
+	d := t.h()
+	d.Write([]byte{1})
+	d.Write(t.state)
+	t.state = d.Sum(nil)
+	return binary.LittleEndian.Uint64(t.state)
+}
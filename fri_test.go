@@ -0,0 +1,172 @@
+package gf
+
+import (
+	"crypto/sha512"
+	"errors"
+	"testing"
+)
+
+func TestFRIDomainFolding(t *testing.T) {
+	m := 10
+	ensureDefaultBasis(m)
+	G := defaultBasis().combinations
+	for j := 0; j < 1<<(m-1); j++ {
+		s := G[2*j]
+		if G[2*j+1] != s^1 {
+			t.Fatal("paired points must differ by one", j)
+		}
+		if mul64(s, s)^s != G[j] {
+			t.Fatal("x^2 + x must map point 2j to j", j)
+		}
+	}
+}
+
+// friEvals evaluates a random polynomial of degree less than d over n points.
+func friEvals(n, d int) []uint64 {
+	ensureDefaultBasis(log2Floor(n))
+	p := newEmptyPoly(n)
+	copy(p.a, randPoly(d).a)
+	_, _ = p.lfft()
+	return p.a
+}
+
+func TestFRI(t *testing.T) {
+	for _, cfg := range []FRIConfig{
+		DefaultFRIConfig,
+		{LogBlowup: 1, Queries: 80, FinalDegree: 4},
+		{LogBlowup: 3, Queries: 30, FinalDegree: 2, Hash: sha512.New},
+	} {
+		f, err := NewFRI(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 1 << 10
+		d := n >> cfg.LogBlowup
+		for _, deg := range []int{1, d / 3, d} {
+			proof, err := f.Prove(friEvals(n, deg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := f.Verify(n, proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(root) != string(proof.Commitment()) {
+				t.Fatal("commitment of evaluations must be returned")
+			}
+			if _, err := f.Verify(n/2, proof); err == nil {
+				t.Fatal("proof must be rejected for another length")
+			}
+		}
+	}
+	if _, err := NewFRI(FRIConfig{LogBlowup: 2, Queries: 10, FinalDegree: 3}); err == nil {
+		t.Fatal("final degree must be a power of two")
+	}
+	if _, err := NewFRI(FRIConfig{LogBlowup: 0, Queries: 10, FinalDegree: 1}); err == nil {
+		t.Fatal("rate one must be rejected")
+	}
+}
+
+func TestFRITamperedProof(t *testing.T) {
+	f, err := NewFRI(DefaultFRIConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 1 << 9
+	evals := friEvals(n, n/4)
+	for _, tamper := range []func(p *FRIProof){
+		func(p *FRIProof) { p.Final[0] ^= 1 },
+		func(p *FRIProof) { p.Layers[0].Pairs[0][1] ^= 1 },
+		func(p *FRIProof) { p.Layers[2].Pairs[0][0] ^= 1 },
+		func(p *FRIProof) { p.Roots[1] = p.Roots[0] },
+		func(p *FRIProof) { p.Layers = p.Layers[1:] },
+		func(p *FRIProof) { p.Layers[1].Proof = nil },
+		func(p *FRIProof) { p.Layers[0].Pairs = p.Layers[0].Pairs[1:] },
+	} {
+		proof, err := f.Prove(evals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tamper(proof)
+		if _, err := f.Verify(n, proof); err == nil {
+			t.Fatal("tampered proof must be rejected")
+		}
+	}
+}
+
+func TestFRIVerifyRejectsBadShape(t *testing.T) {
+	f, err := NewFRI(DefaultFRIConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initDefaultBasis(10)
+	if _, err := f.Verify(1<<10, nil); err == nil {
+		t.Fatal("nil proof must be rejected")
+	}
+	if _, err := f.Verify(2*MaxShards, &FRIProof{}); err == nil {
+		t.Fatal("length exceeding MaxShards must be rejected")
+	}
+	// a proof with the expected number of layers but no openings
+	n := MaxShards
+	r, err := f.rounds(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := &FRIProof{Final: make([]uint64, (n>>r)>>DefaultFRIConfig.LogBlowup)}
+	for i := 0; i < r; i++ {
+		proof.Roots = append(proof.Roots, make([]byte, 32))
+		proof.Layers = append(proof.Layers, FRILayerOpening{})
+	}
+	if _, err := f.Verify(n, proof); err == nil {
+		t.Fatal("proof without openings must be rejected")
+	}
+	if defaultBasis().m != 10 {
+		t.Fatal("default basis must not grow for a malformed proof")
+	}
+}
+
+func TestFRIRejectsHighDegree(t *testing.T) {
+	f, err := NewFRI(DefaultFRIConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 1 << 10
+	d := n >> DefaultFRIConfig.LogBlowup
+
+	// a low degree function with a quarter of its values replaced
+	corrupted := friEvals(n, d)
+	for i := 0; i < n; i += 4 {
+		corrupted[i] = randGF64()
+	}
+	for _, evals := range [][]uint64{friEvals(n, d+1), friEvals(n, n), corrupted} {
+		if _, err := f.Prove(evals); !errors.Is(err, ErrHighDegree) {
+			t.Fatal("prover must refuse high degree evaluations", err)
+		}
+		// a cheating prover drops high coefficients of the last layer
+		proof, high, err := f.prove(evals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(high) == 0 {
+			t.Fatal("last layer must have coefficients above degree bound")
+		}
+		if _, err := f.Verify(n, proof); !errors.Is(err, ErrHighDegree) {
+			t.Fatal("high degree evaluations must be rejected", err)
+		}
+	}
+}
+
+func BenchmarkFRIProve(b *testing.B) {
+	f, err := NewFRI(DefaultFRIConfig)
+	if err != nil {
+		b.Fatal(err)
+	}
+	n := 1 << 16
+	evals := friEvals(n, n/4)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Prove(evals); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
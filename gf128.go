@@ -0,0 +1,102 @@
+package gf
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// GF(2^128) in polynomial basis under the irreducible polynomial of GHASH
+// R = x^128 + x^7 + x^2 + x + 1
+// Element is kept in two words, coefficient of x^i being bit i % 64 of word i / 64.
+// GHASH orders bits reflected, that is the most significant bit of the first
+// byte is the coefficient of x^0, see gf128FromGHASH and ghashBytes.
+type gf128 [2]uint64
+
+// gf128MOD is the lower part of the irreducible polynomial
+// r = x^7 + x^2 + x + 1
+var gf128MOD uint64 = 0x87
+
+// randGF128 generates a new random non zero GF(2^128) element.
+func randGF128() gf128 {
+	buf := make([]byte, 16)
+	var e gf128
+	for e == (gf128{}) {
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		e = gf128{binary.LittleEndian.Uint64(buf), binary.LittleEndian.Uint64(buf[8:])}
+	}
+	return e
+}
+
+func (a gf128) String() string {
+	return fmt.Sprintf("%#16.16x%16.16x", a[1], a[0])
+}
+
+func one128() gf128 {
+	return gf128{1, 0}
+}
+
+func mul128v(a, b gf128) gf128 {
+	var c gf128
+	mul128(&c, &a, &b)
+	return c
+}
+
+func square128(a gf128) gf128 {
+	var c gf128
+	mul128(&c, &a, &a)
+	return c
+}
+
+// inverse128 computes a^(2^128 - 2) with Itoh-Tsujii chain of
+// b_k = a^(2^k - 1), b_2k = b_k^(2^k) b_k and b_(k+1) = b_k^2 a.
+// Inverse of zero is zero.
+func inverse128(a gf128) gf128 {
+	b := a
+	for k := 1; k < 127; {
+		t := b
+		for i := 0; i < k; i++ {
+			t = square128(t)
+		}
+		b = mul128v(t, b)
+		b = mul128v(square128(b), a)
+		k = 2*k + 1
+	}
+	return square128(b)
+}
+
+// mul128Naive multiplies two GF(2^128) elements with shift and add method.
+func mul128Naive(a, b gf128) gf128 {
+	var r gf128
+	shifted := a
+	for i := 0; i < 128; i++ {
+		if bit128(b, i) == 1 {
+			r[0] ^= shifted[0]
+			r[1] ^= shifted[1]
+		}
+		carry := shifted[1] >> 63
+		shifted[1] = shifted[1]<<1 | shifted[0]>>63
+		shifted[0] <<= 1
+		if carry == 1 {
+			shifted[0] ^= gf128MOD
+		}
+	}
+	return r
+}
+
+// gf128FromGHASH reads an element from a 16 byte block in GHASH bit order.
+func gf128FromGHASH(b []byte) gf128 {
+	return gf128{
+		bits.Reverse64(binary.BigEndian.Uint64(b)),
+		bits.Reverse64(binary.BigEndian.Uint64(b[8:])),
+	}
+}
+
+// ghashBytes returns the element as a 16 byte block in GHASH bit order.
+func (a gf128) ghashBytes() []byte {
+	b := binary.BigEndian.AppendUint64(nil, bits.Reverse64(a[0]))
+	return binary.BigEndian.AppendUint64(b, bits.Reverse64(a[1]))
+}
@@ -0,0 +1,185 @@
+package gf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"testing"
+)
+
+func TestGF128MultiplicationCrossAgainstNaive(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		a, b := randGF128(), randGF128()
+		c0 := mul128v(a, b)
+		c1 := mul128Naive(a, b)
+		if c0 != c1 {
+			t.Fatalf("a, b, c0, c1 %s, %s, %s, %s", a, b, c0, c1)
+		}
+	}
+	// reduction of the top coefficient
+	a := gf128{0, 1 << 63}
+	if mul128v(a, a) != mul128Naive(a, a) {
+		t.Fatal("x^254 is badly reduced")
+	}
+}
+
+func TestGF128Properties(t *testing.T) {
+	one := one128()
+	for i := 0; i < 1000; i++ {
+		a, b, c := randGF128(), randGF128(), randGF128()
+		if mul128v(a, one) != a || mul128v(a, gf128{}) != (gf128{}) {
+			t.Fatal("a * 1 == a, a * 0 == 0")
+		}
+		if mul128v(a, b) != mul128v(b, a) {
+			t.Fatal("a * b == b * a")
+		}
+		if mul128v(mul128v(a, b), c) != mul128v(mul128v(a, c), b) {
+			t.Fatal("(a * b) * c == (a * c) * b")
+		}
+		bc := gf128{b[0] ^ c[0], b[1] ^ c[1]}
+		ab, ac := mul128v(a, b), mul128v(a, c)
+		if mul128v(a, bc) != (gf128{ab[0] ^ ac[0], ab[1] ^ ac[1]}) {
+			t.Fatal("a * (b + c) == a * b + a * c")
+		}
+		if mul128v(a, inverse128(a)) != one {
+			t.Fatal("a * a ^ -1 == 1")
+		}
+	}
+	if inverse128(gf128{}) != (gf128{}) {
+		t.Fatal("inverse of zero must be zero")
+	}
+}
+
+// TestGF128GHASH computes the tag of AES-GCM over additional data
+// with GHASH in polynomial representation.
+func TestGF128GHASH(t *testing.T) {
+	key := make([]byte, 16)
+	nonce := make([]byte, 12)
+	aad := make([]byte, 48)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := range aad {
+		aad[i] = byte(3*i + 1)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := gcm.Seal(nil, nonce, nil, aad)
+
+	hb := make([]byte, 16)
+	block.Encrypt(hb, hb)
+	h := gf128FromGHASH(hb)
+	var y gf128
+	lengths := binary.BigEndian.AppendUint64(nil, uint64(8*len(aad)))
+	lengths = binary.BigEndian.AppendUint64(lengths, 0)
+	for _, b := range [][]byte{aad[:16], aad[16:32], aad[32:], lengths} {
+		x := gf128FromGHASH(b)
+		y = mul128v(gf128{y[0] ^ x[0], y[1] ^ x[1]}, h)
+	}
+	j0 := append(append([]byte{}, nonce...), 0, 0, 0, 1)
+	block.Encrypt(j0, j0)
+	want := y.ghashBytes()
+	for i := range want {
+		want[i] ^= j0[i]
+	}
+	if !bytes.Equal(tag, want) {
+		t.Fatalf("GHASH mismatch %x %x", tag, want)
+	}
+	if gf128FromGHASH(h.ghashBytes()) != h {
+		t.Fatal("GHASH bit order must round trip")
+	}
+}
+
+func TestTower128(t *testing.T) {
+	one := tower128{1, 0}
+	y := tower128{0, 1}
+	if squareTower(y) != (tower128{towerAlpha, 1}) {
+		t.Fatal("y^2 == y + α")
+	}
+	for i := 0; i < 1000; i++ {
+		a, b, c := randTower128(), randTower128(), randTower128()
+		if mulTower(a, one) != a {
+			t.Fatal("a * 1 == a")
+		}
+		if mulTower(a, b) != mulTower(b, a) {
+			t.Fatal("a * b == b * a")
+		}
+		if mulTower(mulTower(a, b), c) != mulTower(mulTower(a, c), b) {
+			t.Fatal("(a * b) * c == (a * c) * b")
+		}
+		if squareTower(a) != mulTower(a, a) {
+			t.Fatal("a^2 == a * a")
+		}
+		if mulTower(a, inverseTower(a)) != one {
+			t.Fatal("a * a ^ -1 == 1")
+		}
+		if mulTower(tower128{a[0], 0}, tower128{b[0], 0}) != (tower128{mul64(a[0], b[0]), 0}) {
+			t.Fatal("GF(2^64) must be a subfield")
+		}
+	}
+}
+
+func TestTower128Isomorphism(t *testing.T) {
+	if towerToGF128(tower128{1, 0}) != one128() {
+		t.Fatal("one must be mapped to one")
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := randTower128(), randTower128()
+		pa, pb := towerToGF128(a), towerToGF128(b)
+		if towerToGF128(mulTower(a, b)) != mul128v(pa, pb) {
+			t.Fatal("multiplication must be preserved")
+		}
+		if towerToGF128(inverseTower(a)) != inverse128(pa) {
+			t.Fatal("inverse must be preserved")
+		}
+		if towerToGF128(tower128{a[0] ^ b[0], a[1] ^ b[1]}) != (gf128{pa[0] ^ pb[0], pa[1] ^ pb[1]}) {
+			t.Fatal("addition must be preserved")
+		}
+		if gf128ToTower(pa) != a {
+			t.Fatal("maps must be inverses")
+		}
+		c := randGF128()
+		if gf128ToTower(mul128v(c, pa)) != mulTower(gf128ToTower(c), a) {
+			t.Fatal("inverse map must preserve multiplication")
+		}
+	}
+}
+
+func BenchmarkGF128Mul(t *testing.B) {
+	r0, r1 := randGF128(), randGF128()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		mul128(&r0, &r0, &r1)
+	}
+}
+
+func BenchmarkTower128Mul(t *testing.B) {
+	r0, r1 := randTower128(), randTower128()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		r0 = mulTower(r0, r1)
+	}
+}
+
+func BenchmarkGF128Inverse(t *testing.B) {
+	r0 := randGF128()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		r0 = inverse128(r0)
+	}
+}
+
+func BenchmarkTower128Inverse(t *testing.B) {
+	r0 := randTower128()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		r0 = inverseTower(r0)
+	}
+}
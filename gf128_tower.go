@@ -0,0 +1,190 @@
+package gf
+
+import (
+	"errors"
+	"sync"
+)
+
+// GF(2^128) as a degree two extension of GF(2^64)
+// y^2 + y + α where α = b_0 is the first Cantor basis of default basis.
+// Polynomial is irreducible since Tr(α) = 1 and its root y satisfies
+// y^2 + y = b_0, so that y extends the Cantor basis by one more element.
+// Element is a_0 + a_1 y kept as {a_0, a_1}.
+type tower128 [2]uint64
+
+const towerAlpha = defaultBasisGenerator
+
+func randTower128() tower128 {
+	return tower128{randGF64(), randGF64()}
+}
+
+// mulTower multiplies with Karatsuba, three multiplications and one by α
+// (a_0 + a_1 y)(b_0 + b_1 y) = a_0 b_0 + α a_1 b_1 + (a_0 b_1 + a_1 b_0 + a_1 b_1) y
+func mulTower(a, b tower128) tower128 {
+	t0 := mul64(a[0], b[0])
+	t2 := mul64(a[1], b[1])
+	t1 := mul64(a[0]^a[1], b[0]^b[1])
+	return tower128{t0 ^ mul64(towerAlpha, t2), t1 ^ t0}
+}
+
+// squareTower squares as (a_0 + a_1 y)^2 = a_0^2 + α a_1^2 + a_1^2 y
+func squareTower(a tower128) tower128 {
+	s0, s1 := square64(a[0]), square64(a[1])
+	return tower128{s0 ^ mul64(towerAlpha, s1), s1}
+}
+
+// inverseTower inverts with the conjugate a_0 + a_1 + a_1 y, which is image
+// of Frobenius y -> y^(2^64) = y + 1, and the norm
+// N(a) = a_0^2 + a_0 a_1 + α a_1^2 in GF(2^64). Inverse of zero is zero.
+func inverseTower(a tower128) tower128 {
+	n := square64(a[0]) ^ mul64(a[0], a[1]) ^ mul64(towerAlpha, square64(a[1]))
+	n = inverse(n)
+	return tower128{mul64(a[0]^a[1], n), mul64(a[1], n)}
+}
+
+// Tower and polynomial representations are related by a GF(2) linear field
+// isomorphism. Subfield GF(2^64) of polynomial representation is reached
+// with a generator z of it whose minimal polynomial over
+// GF(2) has a root w in GF(2^64) so that w^i -> z^i is an embedding.
+// Then y is mapped to a root of Y^2 + Y = image of α. Maps are built once.
+var (
+	towerIsoOnce           sync.Once
+	towerToPoly, polyToTow *linearMap
+	towerIsoErr            error
+)
+
+// towerToGF128 maps an element in tower representation to polynomial representation.
+func towerToGF128(a tower128) gf128 {
+	towerIsoOnce.Do(initTowerIso)
+	if towerIsoErr != nil {
+		panic(towerIsoErr)
+	}
+	return gf128(towerToPoly.apply(a))
+}
+
+// gf128ToTower maps an element in polynomial representation to tower representation.
+func gf128ToTower(a gf128) tower128 {
+	towerIsoOnce.Do(initTowerIso)
+	if towerIsoErr != nil {
+		panic(towerIsoErr)
+	}
+	return tower128(polyToTow.apply(a))
+}
+
+func initTowerIso() {
+	// z = r + r^(2^64) is in the fixed field of Frobenius which is the subfield GF(2^64)
+	phi, err := embedGF64([2]uint64(one128()), gf128Mul, func(c int) [2]uint64 {
+		var r [2]uint64
+		r[c>>6] = 1 << (c & 63)
+		f := gf128Frobenius64(r)
+		return [2]uint64{r[0] ^ f[0], r[1] ^ f[1]}
+	})
+	if err != nil {
+		towerIsoErr = err
+		return
+	}
+	alpha := phi.apply([2]uint64{towerAlpha, 0})
+	// Y -> Y^2 + Y is GF(2) linear
+	s := &gf2Span{}
+	for i := 0; i < 128; i++ {
+		var e gf128
+		e[i>>6] = 1 << (i & 63)
+		l := square128(e)
+		s.insert([2]uint64{l[0] ^ e[0], l[1] ^ e[1]})
+	}
+	comb, ok := s.solve(alpha)
+	if !ok {
+		towerIsoErr = errors.New("no root of y^2 + y + α")
+		return
+	}
+	psi := gf128{comb[0], comb[1]}
+	m := &linearMap{}
+	for i := 0; i < 64; i++ {
+		m[i] = phi[i]
+		m[64+i] = mul128v(phi[i], psi)
+	}
+	inv, ok := m.inverse()
+	if !ok {
+		towerIsoErr = errors.New("tower map is not bijective")
+		return
+	}
+	towerToPoly, polyToTow = m, inv
+}
+
+func gf128Mul(a, b [2]uint64) [2]uint64 {
+	return mul128v(a, b)
+}
+
+// gf128Frobenius64 returns a^(2^64).
+func gf128Frobenius64(a [2]uint64) [2]uint64 {
+	t := gf128(a)
+	for i := 0; i < 64; i++ {
+		t = square128(t)
+	}
+	return t
+}
+
+// embedGF64 finds a field embedding of GF(2^64) into a field of 128 bit
+// vectors with given one and multiplication, and returns it as a linear map
+// of which first 64 images are used. candidate(c) for c < 128 are elements
+// of the subfield GF(2^64) of the target, the first one whose minimal
+// polynomial is of degree 64 generates the subfield.
+func embedGF64(one [2]uint64, mul func(a, b [2]uint64) [2]uint64, candidate func(c int) [2]uint64) (*linearMap, error) {
+	for c := 0; c < 128; c++ {
+		z := candidate(c)
+		// minimal polynomial of z is the first dependency of its powers
+		s := &gf2Span{}
+		pow := one
+		var minimal []uint64
+		for i := 0; i <= 64; i++ {
+			if comb, ok := s.insert(pow); !ok {
+				if i == 64 {
+					minimal = comb
+				}
+				break
+			}
+			pow = mul(pow, z)
+		}
+		if minimal == nil {
+			continue
+		}
+		coeffs := make([]uint64, 65)
+		for i := 0; i < 64; i++ {
+			coeffs[i] = minimal[0] >> i & 1
+		}
+		coeffs[64] = 1
+		roots, _, err := newPoly(coeffs).roots()
+		if err != nil {
+			return nil, err
+		}
+		if len(roots) != 64 {
+			return nil, errors.New("minimal polynomial does not split in GF(2^64)")
+		}
+		w := roots[0]
+		// w^i -> z^i, images of unit vectors are read from w^i combinations
+		ws := &gf2Span{}
+		zs := make([][2]uint64, 64)
+		wi, zi := uint64(1), one
+		for i := 0; i < 64; i++ {
+			ws.insert([2]uint64{wi, 0})
+			zs[i] = zi
+			wi = mul64(wi, w)
+			zi = mul(zi, z)
+		}
+		m := &linearMap{}
+		for j := 0; j < 64; j++ {
+			comb, ok := ws.solve([2]uint64{1 << j, 0})
+			if !ok {
+				return nil, errors.New("powers of root do not span GF(2^64)")
+			}
+			for i := 0; i < 64; i++ {
+				if comb[0]>>i&1 == 1 {
+					m[j][0] ^= zs[i][0]
+					m[j][1] ^= zs[i][1]
+				}
+			}
+		}
+		return m, nil
+	}
+	return nil, errors.New("no generator of subfield GF(2^64) is found")
+}
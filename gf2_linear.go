@@ -0,0 +1,95 @@
+package gf
+
+// gf2Span is the span of 128 bit vectors over GF(2) in echelon form. Each
+// row keeps the combination of inserted vectors it is made of, so that
+// linear dependencies and solutions of linear systems can be read back.
+type gf2Span struct {
+	rows   [][2]uint64
+	combs  [][]uint64
+	pivots []int
+	// count is number of inserted vectors
+	count int
+}
+
+func bit128(v [2]uint64, i int) uint64 {
+	return v[i>>6] >> (i & 63) & 1
+}
+
+// reduce reduces v with rows of the span and returns the residual and the
+// combination of inserted vectors that is subtracted.
+func (s *gf2Span) reduce(v [2]uint64) ([2]uint64, []uint64) {
+	comb := make([]uint64, (s.count+64)/64)
+	for j, row := range s.rows {
+		if bit128(v, s.pivots[j]) == 1 {
+			v[0] ^= row[0]
+			v[1] ^= row[1]
+			for t := range s.combs[j] {
+				comb[t] ^= s.combs[j][t]
+			}
+		}
+	}
+	return v, comb
+}
+
+// insert adds v to the span. If v is already in the span, it returns the
+// combination of previously inserted vectors that sums to v and false.
+func (s *gf2Span) insert(v [2]uint64) ([]uint64, bool) {
+	r, comb := s.reduce(v)
+	index := s.count
+	s.count++
+	if r == [2]uint64{} {
+		return comb, false
+	}
+	pivot := 0
+	for bit128(r, pivot) == 0 {
+		pivot++
+	}
+	comb[index>>6] ^= 1 << (index & 63)
+	s.rows = append(s.rows, r)
+	s.combs = append(s.combs, comb)
+	s.pivots = append(s.pivots, pivot)
+	return nil, true
+}
+
+// solve returns a combination of inserted vectors that sums to v.
+func (s *gf2Span) solve(v [2]uint64) ([]uint64, bool) {
+	r, comb := s.reduce(v)
+	return comb, r == [2]uint64{}
+}
+
+// rank returns dimension of the span.
+func (s *gf2Span) rank() int {
+	return len(s.rows)
+}
+
+// linearMap is a GF(2) linear map of 128 bit vectors given by images of unit vectors.
+type linearMap [128][2]uint64
+
+func (m *linearMap) apply(v [2]uint64) [2]uint64 {
+	var r [2]uint64
+	for i := 0; i < 128; i++ {
+		if bit128(v, i) == 1 {
+			r[0] ^= m[i][0]
+			r[1] ^= m[i][1]
+		}
+	}
+	return r
+}
+
+// inverse returns inverse of the map, which is expected to be bijective.
+func (m *linearMap) inverse() (*linearMap, bool) {
+	s := &gf2Span{}
+	for i := 0; i < 128; i++ {
+		if _, ok := s.insert(m[i]); !ok {
+			return nil, false
+		}
+	}
+	inv := &linearMap{}
+	for i := 0; i < 128; i++ {
+		var unit [2]uint64
+		unit[i>>6] = 1 << (i & 63)
+		comb, _ := s.solve(unit)
+		inv[i] = [2]uint64{comb[0], comb[1]}
+	}
+	return inv, true
+}
@@ -0,0 +1,289 @@
+package gf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// sqrtPoly returns g such that g^2 = p. It expects all odd indexed
+// coefficients of p to be zero, which is the case when derivative vanishes.
+func (p *poly) sqrtPoly() (*poly, error) {
+	n := p.length()
+	g := newEmptyPoly((n + 1) / 2)
+	for i := 0; i < n; i++ {
+		if i&1 == 1 {
+			if p.a[i] != 0 {
+				return nil, errors.New("polynomial is not a perfect square")
+			}
+			continue
+		}
+		g.a[i/2] = sqrt(p.a[i])
+	}
+	return g, nil
+}
+
+// isOne tells if polynomial is the constant one.
+func (p *poly) isOne() bool {
+	q := p.clone()
+	q.trimZeros()
+	return q.length() == 1 && q.a[0] == 1
+}
+
+// frobeniusMod returns p ^ (2 ^ (64 * k)) mod f.
+func (p *poly) frobeniusMod(f *poly, k int) (*poly, error) {
+	var err error
+	r := p
+	for i := 0; i < 64*k; i++ {
+		if r, err = r.squareMod(f); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// factor factorizes the polynomial into monic irreducible factors
+// and returns them together with their multiplicities.
+// Leading coefficient of the polynomial is not included.
+// Square-free decomposition is followed by distinct-degree factorization
+// and Cantor-Zassenhaus equal-degree splitting with trace map
+// which is the variant for characteristic two.
+func (p *poly) factor() ([]*poly, []int, error) {
+	f := p.clone().monic()
+	if f.length() == 0 {
+		return nil, nil, errors.New("zero polynomial cannot be factorized")
+	}
+	sff, sffMult, err := f.squareFree()
+	if err != nil {
+		return nil, nil, err
+	}
+	factors, mult := []*poly{}, []int{}
+	for i := 0; i < len(sff); i++ {
+		ddf, degrees, err := sff[i].distinctDegree()
+		if err != nil {
+			return nil, nil, err
+		}
+		for j := 0; j < len(ddf); j++ {
+			edf, err := ddf[j].equalDegree(degrees[j])
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, g := range edf {
+				factors = append(factors, g)
+				mult = append(mult, sffMult[i])
+			}
+		}
+	}
+	sortFactors(factors, mult)
+	return factors, mult, nil
+}
+
+// squareFree decomposes monic polynomial into square free and pairwise coprime
+// factors with their multiplicities. Where derivative vanishes
+// polynomial is a square and its square root is taken
+// with repeated squaring of the coefficients.
+func (p *poly) squareFree() ([]*poly, []int, error) {
+	factors, mult := []*poly{}, []int{}
+	if p.degree() < 1 {
+		return factors, mult, nil
+	}
+	c := p.clone()
+	d := p.derivative()
+	if d.length() != 0 {
+		var err error
+		if c, err = gcd(p, d); err != nil {
+			return nil, nil, err
+		}
+		w, _, err := p.divMod(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := 1; !w.isOne(); i++ {
+			y, err := gcd(w, c)
+			if err != nil {
+				return nil, nil, err
+			}
+			g, _, err := w.divMod(y)
+			if err != nil {
+				return nil, nil, err
+			}
+			if g.degree() > 0 {
+				factors = append(factors, g.monic())
+				mult = append(mult, i)
+			}
+			w = y
+			if c, _, err = c.divMod(y); err != nil {
+				return nil, nil, err
+			}
+		}
+		if c.isOne() {
+			return factors, mult, nil
+		}
+	}
+	// remaining part is a perfect square
+	s, err := c.monic().sqrtPoly()
+	if err != nil {
+		return nil, nil, err
+	}
+	sFactors, sMult, err := s.squareFree()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 0; i < len(sFactors); i++ {
+		factors = append(factors, sFactors[i])
+		mult = append(mult, 2*sMult[i])
+	}
+	return factors, mult, nil
+}
+
+// distinctDegree splits monic square free polynomial into products of irreducible
+// factors of equal degree, degree of factors of each product is also returned.
+func (p *poly) distinctDegree() ([]*poly, []int, error) {
+	factors, degrees := []*poly{}, []int{}
+	x := newPoly([]uint64{0, 1})
+	fs := p.clone()
+	h, err := x.mod(fs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 1; fs.degree() >= 2*i; i++ {
+		// h = x ^ (q ^ i) mod fs
+		if h, err = h.frobeniusMod(fs, 1); err != nil {
+			return nil, nil, err
+		}
+		hx := h.clone()
+		hx.expand(2)
+		hx.add(x)
+		g, err := gcd(fs, hx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if g.isOne() {
+			continue
+		}
+		factors = append(factors, g)
+		degrees = append(degrees, i)
+		if fs, _, err = fs.divMod(g); err != nil {
+			return nil, nil, err
+		}
+		if h, err = h.mod(fs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if fs.degree() > 0 {
+		factors = append(factors, fs.monic())
+		degrees = append(degrees, fs.degree())
+	}
+	return factors, degrees, nil
+}
+
+// equalDegree splits monic square free polynomial whose irreducible factors are all
+// of degree d. In characteristic two Cantor-Zassenhaus uses the trace map
+// T(a) = a + a^2 + ... + a^(2^(64d-1)) mod p instead of a^((q^d-1)/2)
+// and gcd(p, T(a)) is a proper factor with probability about one half
+// for a random a.
+func (p *poly) equalDegree(d int) ([]*poly, error) {
+	n := p.degree()
+	if n <= d {
+		return []*poly{p.monic()}, nil
+	}
+	for {
+		a := randPoly(n)
+		a.trimZeros()
+		if a.degree() < 1 {
+			continue
+		}
+		t := a.clone()
+		u := a
+		var err error
+		for i := 1; i < 64*d; i++ {
+			if u, err = u.squareMod(p); err != nil {
+				return nil, err
+			}
+			t.expand(u.length())
+			t.add(u)
+		}
+		t.trimZeros()
+		g, err := gcd(p, t)
+		if err != nil {
+			return nil, err
+		}
+		if g.degree() < 1 || g.degree() == n {
+			continue
+		}
+		h, _, err := p.divMod(g)
+		if err != nil {
+			return nil, err
+		}
+		g0, err := g.equalDegree(d)
+		if err != nil {
+			return nil, err
+		}
+		g1, err := h.monic().equalDegree(d)
+		if err != nil {
+			return nil, err
+		}
+		return append(g0, g1...), nil
+	}
+}
+
+// isIrreducible applies Rabin style test to the polynomial, that is
+// f of degree n is irreducible iff gcd(f, x^(q^i) - x) = 1 for all i <= n/2.
+func (p *poly) isIrreducible() (bool, error) {
+	f := p.clone().monic()
+	n := f.degree()
+	if n < 1 {
+		return false, nil
+	}
+	x := newPoly([]uint64{0, 1})
+	h, err := x.mod(f)
+	if err != nil {
+		return false, err
+	}
+	for i := 1; i <= n/2; i++ {
+		if h, err = h.frobeniusMod(f, 1); err != nil {
+			return false, err
+		}
+		hx := h.clone()
+		hx.expand(2)
+		hx.add(x)
+		g, err := gcd(f, hx)
+		if err != nil {
+			return false, err
+		}
+		if !g.isOne() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sortFactors orders factors by degree then by coefficients
+// so that factorization output is deterministic.
+func sortFactors(factors []*poly, mult []int) {
+	key := func(p *poly) []byte {
+		buf := make([]byte, 8*p.length())
+		for i := 0; i < p.length(); i++ {
+			binary.BigEndian.PutUint64(buf[8*i:], p.a[p.length()-1-i])
+		}
+		return buf
+	}
+	idx := make([]int, len(factors))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		a, b := factors[idx[i]], factors[idx[j]]
+		if a.length() != b.length() {
+			return a.length() < b.length()
+		}
+		return bytes.Compare(key(a), key(b)) < 0
+	})
+	f, m := make([]*poly, len(idx)), make([]int, len(idx))
+	for i, j := range idx {
+		f[i], m[i] = factors[j], mult[j]
+	}
+	copy(factors, f)
+	copy(mult, m)
+}
@@ -0,0 +1,129 @@
+package gf
+
+import (
+	"testing"
+)
+
+// randIrreducible generates a random monic irreducible polynomial of degree d.
+func randIrreducible(t *testing.T, d int) *poly {
+	for {
+		f := randPoly(d + 1)
+		f.a[d] = 1
+		ok, err := f.isIrreducible()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			return f
+		}
+	}
+}
+
+func TestIsIrreducible(t *testing.T) {
+	c := randGF64()
+	for trace(c) != 1 {
+		c = randGF64()
+	}
+	ok, err := newPoly([]uint64{c, 1, 1}).isIrreducible()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("x^2 + x + c with Tr(c) = 1 is irreducible")
+	}
+	c = randGF64()
+	for trace(c) != 0 {
+		c = randGF64()
+	}
+	ok, err = newPoly([]uint64{c, 1, 1}).isIrreducible()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("x^2 + x + c with Tr(c) = 0 splits")
+	}
+	for i := 0; i < 5; i++ {
+		f := randIrreducible(t, 3)
+		r, _, err := f.roots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r) != 0 {
+			t.Fatal("irreducible cubic must have no roots")
+		}
+	}
+}
+
+func TestPolyFactorization(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		planted := []*poly{
+			newPoly([]uint64{randGF64(), 1}),
+			newPoly([]uint64{randGF64(), 1}),
+			randIrreducible(t, 2),
+			randIrreducible(t, 2),
+			randIrreducible(t, 3),
+			randIrreducible(t, 4),
+		}
+		plantedMult := []int{1, 3, 2, 1, 1, 4}
+		f := newPoly([]uint64{randGF64()})
+		for j := 0; j < len(planted); j++ {
+			for k := 0; k < plantedMult[j]; k++ {
+				f.mulN(planted[j])
+			}
+		}
+		factors, mult, err := f.factor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(factors) != len(planted) {
+			t.Fatalf("expected %d factors, got %d", len(planted), len(factors))
+		}
+		g := newPoly([]uint64{1})
+		for j := 0; j < len(factors); j++ {
+			ok, err := factors[j].isIrreducible()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatal("factor expected to be irreducible", j)
+			}
+			found := false
+			for k := 0; k < len(planted); k++ {
+				if planted[k].equalInCoeff(factors[j]) && planted[k].length() == factors[j].length() {
+					if plantedMult[k] != mult[j] {
+						t.Fatal("bad multiplicity", j)
+					}
+					found = true
+				}
+			}
+			if !found {
+				t.Fatal("unexpected factor", j)
+			}
+			for k := 0; k < mult[j]; k++ {
+				g.mulN(factors[j])
+			}
+		}
+		if !g.equalInCoeff(f.clone().monic()) {
+			t.Fatal("product of factors must equal to the monic polynomial")
+		}
+	}
+}
+
+func TestSquareFreeOfSquare(t *testing.T) {
+	// derivative of a perfect square vanishes
+	g := randIrreducible(t, 3)
+	f := g.clone()
+	f.mulN(g)
+	f.mulN(g)
+	f.mulN(g)
+	if f.derivative().length() != 0 {
+		t.Fatal("derivative of a square must vanish")
+	}
+	factors, mult, err := f.factor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(factors) != 1 || mult[0] != 4 || !factors[0].equalInCoeff(g) {
+		t.Fatal("factorization of a perfect square failed")
+	}
+}
@@ -95,6 +95,26 @@ func exp(a uint64, e uint64) uint64 {
 	return r
 }
 
+// sqrt computes square root of an element as a^(2^63)
+// since squaring is a bijection in binary fields.
+func sqrt(a uint64) uint64 {
+	for i := 0; i < 63; i++ {
+		squareassign64(&a)
+	}
+	return a
+}
+
+// trace computes absolute trace of an element which is
+// Tr(a) = a + a^2 + a^4 + ... + a^(2^63) and it is either 0 or 1.
+func trace(a uint64) uint64 {
+	t := a
+	for i := 1; i < 64; i++ {
+		squareassign64(&a)
+		t ^= a
+	}
+	return t
+}
+
 // mulNaive multiplies two GF(16) element with shift and add method
 func mulNaive(e0, e1 uint64) uint64 {
 	var result uint64 = 0
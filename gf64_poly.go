@@ -3,6 +3,7 @@ package gf
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 )
 
 type basis struct {
@@ -16,14 +17,21 @@ type basis struct {
 // defaultBasisGenerator generates Cantor basis with last bases equals to 1
 const defaultBasisGenerator uint64 = 0xce41e2bee6cbe964
 
-var defaultBasis *basis
+// currentBasis is replaced as a whole rather than modified, so that
+// transforms running while it grows keep reading a complete basis.
+var currentBasis atomic.Pointer[basis]
+
+// defaultBasis returns the current default basis, nil until one is built.
+func defaultBasis() *basis {
+	return currentBasis.Load()
+}
 
 func initDefaultBasis(m int) {
-	var err error
-	defaultBasis, err = newBasis(defaultBasisGenerator, m)
+	b, err := newBasis(defaultBasisGenerator, m)
 	if err != nil {
 		panic(err)
 	}
+	currentBasis.Store(b)
 }
 
 // newBasis given generator generates Cantor bases and
@@ -186,6 +194,152 @@ func (p *poly) substitute(k uint64) *poly {
 	return p
 }
 
+// derivative returns formal derivative of the polynomial as a new polynomial.
+// In characteristic two i * a_i vanishes for even i
+// so that only odd indexed coefficients survive.
+func (p *poly) derivative() *poly {
+	n := p.length()
+	if n < 2 {
+		return newPoly([]uint64{})
+	}
+	d := newEmptyPoly(n - 1)
+	for i := 1; i < n; i += 2 {
+		d.a[i-1] = p.a[i]
+	}
+	d.trimZeros()
+	return d
+}
+
+// compose returns p(q(x)) as a new polynomial. Composition is computed
+// in divide and conquer manner such that p = p0 + x^h * p1 is composed as
+// p0(q) + q^h * p1(q) where h is a power of two and q^h is precomputed.
+func (p *poly) compose(q *poly) (*poly, error) {
+	f := p.clone()
+	f.trimZeros()
+	n := f.length()
+	if n == 0 {
+		return newPoly([]uint64{}), nil
+	}
+	g := q.clone()
+	g.trimZeros()
+	if g.length() == 0 {
+		return newPoly([]uint64{f.a[0]}), nil
+	}
+	// pows[i] = q ^ (2 ^ i)
+	m := log2Ceil(n)
+	pows := make([]*poly, m+1)
+	pows[0] = g
+	for i := 1; i <= m; i++ {
+		pows[i] = pows[i-1].clone()
+		if _, err := pows[i].mul(pows[i-1]); err != nil {
+			return nil, err
+		}
+	}
+	return f.composeRec(pows, m)
+}
+
+func (p *poly) composeRec(pows []*poly, m int) (*poly, error) {
+	n := p.length()
+	if n == 1 {
+		return newPoly([]uint64{p.a[0]}), nil
+	}
+	for 1<<(m-1) >= n {
+		m--
+	}
+	h := 1 << (m - 1)
+	r, err := newPoly(p.a[:h]).composeRec(pows, m-1)
+	if err != nil {
+		return nil, err
+	}
+	r1, err := newPoly(p.a[h:]).composeRec(pows, m-1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r1.mul(pows[m-1]); err != nil {
+		return nil, err
+	}
+	r1.expand(r.length())
+	r1.add(r)
+	r1.trimZeros()
+	return r1, nil
+}
+
+// composeMod returns p(q(x)) mod f as a new polynomial with baby step giant step
+// approach of Brent and Kung. Powers q^0 ... q^(s-1) mod f are precomputed
+// where s is about square root of the length of p, so that only about 2s
+// modular multiplications are needed rather than one for each coefficient of p.
+func (p *poly) composeMod(q, f *poly) (*poly, error) {
+	n := p.length()
+	if n == 0 {
+		return newPoly([]uint64{}), nil
+	}
+	s := 1
+	for s*s < n {
+		s++
+	}
+	// baby steps, q^i mod f for i < s
+	pows := make([]*poly, s+1)
+	var err error
+	if pows[0], err = newPoly([]uint64{1}).mod(f); err != nil {
+		return nil, err
+	}
+	if pows[1], err = q.mod(f); err != nil {
+		return nil, err
+	}
+	for i := 2; i <= s; i++ {
+		if pows[i], err = pows[i-1].mulMod(pows[1], f); err != nil {
+			return nil, err
+		}
+	}
+	giant := pows[s]
+	// p = sum_j P_j(x) x^(s*j) where each P_j is of degree less than s
+	l := f.clone()
+	l.trimZeros()
+	r := newPoly([]uint64{})
+	for j := (n - 1) / s; j >= 0; j-- {
+		if r, err = r.mulMod(giant, f); err != nil {
+			return nil, err
+		}
+		r.expand(l.length() - 1)
+		for i := 0; i < s && j*s+i < n; i++ {
+			c := p.a[j*s+i]
+			if c == 0 {
+				continue
+			}
+			for k := 0; k < pows[i].length(); k++ {
+				r.a[k] ^= mul64(c, pows[i].a[k])
+			}
+		}
+		r.trimZeros()
+	}
+	return r, nil
+}
+
+// taylorShift returns p(x + c) as a new polynomial.
+// It applies (x + c)^(2^i) = x^(2^i) + c^(2^i) so that
+// a block of size 2d is shifted as lo(x + c) + (x^d + c^d) * hi(x + c)
+// which is the same structure that radix conversion follows for x^2 + x.
+func (p *poly) taylorShift(c uint64) *poly {
+	l := p.length()
+	if l == 0 {
+		return newPoly([]uint64{})
+	}
+	n := 1 << log2Ceil(l)
+	r := newEmptyPoly(n)
+	copy(r.a, p.a)
+	cd := c
+	for d := 1; d < n; d <<= 1 {
+		for off := 0; off < n; off += 2 * d {
+			for k := off; k < off+d; k++ {
+				r.a[k] ^= mul64(cd, r.a[k+d])
+			}
+		}
+		squareassign64(&cd)
+	}
+	r.a = r.a[:l]
+	return r
+}
+
 func (p *poly) add(q *poly) {
 	l := len(p.a)
 	if l > len(q.a) {
@@ -242,9 +396,62 @@ func (p *poly) iRadixConversion() error {
 	return nil
 }
 
+// toNovelBasis converts coefficients from monomial basis to the novel
+// polynomial basis of Lin, Chung and Han, X_i(x) = prod ŝ_j(x)^(b_j)
+// where i = sum b_j 2^j and ŝ_j is subspace polynomial s_j normalized
+// to be one at β_j. With Cantor bases Taylor expansion at x^2 + x,
+// that is radix conversion, is exactly this change of basis.
+// Polynomial is padded to a power of two length.
+func (p *poly) toNovelBasis() (*poly, error) {
+	p.expand(1 << p.m())
+	if err := p.radixConversion(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// toMonomialBasis converts coefficients from novel polynomial basis
+// to monomial basis. Polynomial is padded to a power of two length.
+func (p *poly) toMonomialBasis() (*poly, error) {
+	p.expand(1 << p.m())
+	if err := p.iRadixConversion(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// novelDerivative returns formal derivative of a polynomial
+// given in novel polynomial basis as a new polynomial in the same basis.
+// Subspace polynomials are linearized so that ŝ_j' is a constant,
+// and the product rule gives X_i' = sum ŝ_j' X_(i - 2^j) over set bits j of i.
+func (p *poly) novelDerivative() *poly {
+	n := p.length()
+	d := newEmptyPoly(n)
+	if n < 2 {
+		return d
+	}
+	m := log2Ceil(n)
+	ds := make([]uint64, m)
+	for j := 0; j < m; j++ {
+		s := newSubspacePoly(j)
+		ds[j] = mul64(s.c[0], inverse(s.eval(defaultBasis().combinations[1<<j])))
+	}
+	for i := 1; i < n; i++ {
+		if p.a[i] == 0 {
+			continue
+		}
+		for j := 0; j < m; j++ {
+			if i>>j&1 == 1 {
+				d.a[i-1<<j] ^= mul64(ds[j], p.a[i])
+			}
+		}
+	}
+	return d
+}
+
 // fftNaive evaluates polynomial at combinations of default bases.
 func (p *poly) fftNaive() {
-	copy(p.a[:], p.eval(defaultBasis.combinations).a[:])
+	copy(p.a[:], p.eval(defaultBasis().combinations).a[:])
 }
 
 func (p *poly) fft() (*poly, error) {
@@ -261,7 +468,7 @@ func (p *poly) fft() (*poly, error) {
 	// Since basis with the last element 1 is only accepted,
 	// we don't need to calculate twisting operations which are
 	// step 2 and step 4 in GM10 Algorithm 2.
-	G := defaultBasis.subCombinations
+	G := defaultBasis().subCombinations
 
 	// Step 1 in GM10 Algorithm 2.
 	// Linear evaluation at the leafs of the recursions.
@@ -291,14 +498,17 @@ func (p *poly) fft() (*poly, error) {
 	return p, nil
 }
 
-// lfft stands for lazy fft, lfft skips radix conversion phase
+// lfft stands for lazy fft, lfft skips radix conversion phase.
+// Since radix conversion is the change of basis from monomials to
+// the novel polynomial basis, lfft evaluates a polynomial whose
+// coefficients are given in novel polynomial basis.
 func (p *poly) lfft() (*poly, error) {
 	m := p.m()
 	n := p.length()
 	if n != 1<<m {
 		return nil, fmt.Errorf("fft operation expects polynomial length as power of two, %d, %d", m, n)
 	}
-	G := defaultBasis.subCombinations
+	G := defaultBasis().subCombinations
 	halfL := 1 << (m - 1)
 	for i := 0; i < halfL; i++ {
 		p.a[i+halfL] ^= p.a[i]
@@ -322,7 +532,7 @@ func (p *poly) ifft() (*poly, error) {
 	if n != 1<<m {
 		return nil, fmt.Errorf("ifft operation expects polynomial length as power of two, %d, %d", m, n)
 	}
-	G := defaultBasis.subCombinations
+	G := defaultBasis().subCombinations
 	var i, j, k, d int
 	for i = m - 1; i > 0; i-- {
 		d = 1 << (m - 1 - i)
@@ -342,13 +552,15 @@ func (p *poly) ifft() (*poly, error) {
 	return p, nil
 }
 
+// lifft is inverse of lfft, it interpolates evaluations
+// into coefficients in novel polynomial basis.
 func (p *poly) lifft() (*poly, error) {
 	m := p.m()
 	n := p.length()
 	if n != 1<<m {
 		return nil, fmt.Errorf("ifft operation expects polynomial length as power of two, %d, %d", m, n)
 	}
-	G := defaultBasis.subCombinations
+	G := defaultBasis().subCombinations
 	var i, j, k, d int
 	for i = m - 1; i > 0; i-- {
 		d = 1 << (m - 1 - i)
@@ -367,17 +579,30 @@ func (p *poly) lifft() (*poly, error) {
 	return p, nil
 }
 
+// mulN multiplies polynomials with schoolbook method.
 func (p *poly) mulN(q *poly) {
-	r := p.degree() + q.degree() + 1
-	R := make([]uint64, r)
-	for i := 0; i < p.length(); i++ {
-		for j := 0; j < q.length(); j++ {
-			R[i+j] ^= mul64(p.a[i], q.a[j])
-		}
+	if p.length() == 0 || q.length() == 0 {
+		p.a = p.a[:0]
+		return
 	}
-	p.expand(r)
-	for i := 0; i < r; i++ {
-		p.a[i] = R[i]
+	R := make([]uint64, p.degree()+q.degree()+1)
+	schoolbook(R, p.a, q.a)
+	p.a = R
+}
+
+// schoolbook writes a * b into r. r is expected to be
+// at least len(a) + len(b) - 1 long and is overwritten.
+func schoolbook(r, a, b []uint64) {
+	for i := 0; i < len(a)+len(b)-1; i++ {
+		r[i] = 0
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i] == 0 {
+			continue
+		}
+		for j := 0; j < len(b); j++ {
+			r[i+j] ^= mul64(a[i], b[j])
+		}
 	}
 }
 
@@ -408,6 +633,64 @@ func (p *poly) muls4(q *poly) {
 	p.a[0] = mul64(p.a[0], q.a[0])
 }
 
+// mulKaratsuba multiplies polynomials with Karatsuba method.
+// Shorter operand is padded with zeros to the length of the longer one.
+func (p *poly) mulKaratsuba(q *poly) {
+	if p.length() == 0 || q.length() == 0 {
+		p.a = p.a[:0]
+		return
+	}
+	r := p.degree() + q.degree() + 1
+	n := p.length()
+	if q.length() > n {
+		n = q.length()
+	}
+	a, b := make([]uint64, n), make([]uint64, n)
+	copy(a, p.a)
+	copy(b, q.a)
+	R := make([]uint64, 2*n-1)
+	karatsuba(R, a, b, make([]uint64, 4*n+2*log2Ceil(n)))
+	p.a = R[:r]
+}
+
+// karatsuba writes a * b into r where a and b are of the same length n
+// and r is at least 2n - 1 long. Scratch space t is expected to be
+// at least 4n + 2log(n) long. Splitting a = a0 + x^h * a1 and b likewise,
+// middle term is found as (a0 + a1)(b0 + b1) - a0b0 - a1b1
+// which makes three half sized products rather than four.
+func karatsuba(r, a, b, t []uint64) {
+	n := len(a)
+	if n < mulKaratsubaCrossover {
+		schoolbook(r, a, b)
+		return
+	}
+	h := (n + 1) / 2
+	l := n - h
+	a0, a1 := a[:h], a[h:]
+	b0, b1 := b[:h], b[h:]
+	// a0 * b0 and a1 * b1 are placed at their final positions
+	karatsuba(r[:2*h-1], a0, b0, t)
+	r[2*h-1] = 0
+	karatsuba(r[2*h:2*n-1], a1, b1, t)
+	as, bs, z1 := t[:h], t[h:2*h], t[2*h:4*h-1]
+	copy(as, a0)
+	copy(bs, b0)
+	for i := 0; i < l; i++ {
+		as[i] ^= a1[i]
+		bs[i] ^= b1[i]
+	}
+	karatsuba(z1, as, bs, t[4*h-1:])
+	for i := 0; i < 2*h-1; i++ {
+		z1[i] ^= r[i]
+	}
+	for i := 0; i < 2*l-1; i++ {
+		z1[i] ^= r[2*h+i]
+	}
+	for i := 0; i < 2*h-1; i++ {
+		r[h+i] ^= z1[i]
+	}
+}
+
 func (p *poly) mulSample(q *poly) (*poly, error) {
 	// TODO expand?
 	n := p.length()
@@ -420,35 +703,158 @@ func (p *poly) mulSample(q *poly) (*poly, error) {
 	return p, nil
 }
 
+// Crossover table of polynomial multiplication. Operands shorter than
+// mulKaratsubaCrossover are multiplied with schoolbook method,
+// operands shorter than mulFFTCrossover are multiplied with Karatsuba method
+// and longer ones are multiplied in evaluation form with additive FFT.
+// Values are chosen with BenchmarkPolyMulCrossover.
+// If one operand is at least mulSegmentRatio times longer than the other one,
+// longer operand is multiplied in segments of the size of the shorter one.
+const (
+	mulKaratsubaCrossover = 16
+	mulFFTCrossover       = 256
+	mulSegmentRatio       = 2
+)
+
+// mul multiplies polynomials of arbitrary lengths and
+// result is sized as deg(p) + deg(q) + 1.
 func (p *poly) mul(q *poly) (*poly, error) {
+	lp, lq := p.length(), q.length()
+	if lp == 0 || lq == 0 {
+		p.a = p.a[:0]
+		return p, nil
+	}
+	if lp == lq {
+		switch lp {
+		case 1:
+			p.a[0] = mul64(p.a[0], q.a[0])
+			return p, nil
+		case 2:
+			p.muls2(q.clone())
+			return p, nil
+		case 3:
+			p.muls3(q.clone())
+			return p, nil
+		case 4:
+			p.muls4(q.clone())
+			return p, nil
+		}
+	}
+	short, long := lp, lq
+	if short > long {
+		short, long = long, short
+	}
+	if short < mulKaratsubaCrossover && long < mulKaratsubaCrossover*mulSegmentRatio {
+		p.mulN(q)
+		return p, nil
+	}
+	if long >= mulSegmentRatio*short {
+		return p.mulSegmented(q)
+	}
+	if long < mulFFTCrossover || !fftFits(lp+lq-1) {
+		p.mulKaratsuba(q)
+		return p, nil
+	}
+	return p.mulFFT(q)
+}
+
+// fftFits tells if default basis spans a domain large enough
+// to evaluate polynomial of length n.
+func fftFits(n int) bool {
+	b := defaultBasis()
+	return b != nil && b.n >= 1<<log2Ceil(n)
+}
+
+// mulFFT multiplies polynomials in evaluation form. Domain is sized with
+// respect to the length of the product so that no coefficient wraps around.
+func (p *poly) mulFFT(q *poly) (*poly, error) {
+	r := p.length() + q.length() - 1
+	n := 1 << log2Ceil(r)
+	if n < 2 {
+		n = 2
+	}
 	q1 := q.clone()
-	n := p.length()
-	if n == 1 {
-		p.a[0] = mul64(p.a[0], q.a[0])
-	} else if n == 2 {
-		p.muls2(q1)
-	} else if n == 3 {
-		p.muls3(q1)
-	} else if n == 4 {
-		p.muls4(q1)
-	} else {
-		m := p.m()
-		n := 1 << m
-		p.expand(2 * n)
-		q1.expand(2 * n)
-		if _, err := p.fft(); err != nil {
+	p.expand(n)
+	q1.expand(n)
+	if _, err := p.fft(); err != nil {
+		return nil, err
+	}
+	if _, err := q1.fft(); err != nil {
+		return nil, err
+	}
+	if _, err := p.mulSample(q1); err != nil {
+		return nil, err
+	}
+	if _, err := p.ifft(); err != nil {
+		return nil, err
+	}
+	p.a = p.a[:r]
+	return p, nil
+}
+
+// mulSegmented multiplies an unbalanced pair of polynomials.
+// Longer operand is cut into segments and each segment is multiplied
+// with the shorter operand and accumulated at its offset.
+// For long enough operands the shorter one is transformed only once
+// and each segment costs a forward and an inverse FFT.
+func (p *poly) mulSegmented(q *poly) (*poly, error) {
+	a, b := p.a, q.a
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	la, lb := len(a), len(b)
+	R := make([]uint64, la+lb-1)
+	if lb >= mulFFTCrossover && fftFits(2*lb) {
+		n := 1 << log2Ceil(2*lb)
+		// each segment product fits in the domain without wrapping around
+		c := n - lb + 1
+		B := newEmptyPoly(n)
+		copy(B.a, b)
+		if _, err := B.fft(); err != nil {
 			return nil, err
 		}
-		if _, err := q1.fft(); err != nil {
-			return nil, err
+		A := newEmptyPoly(n)
+		for off := 0; off < la; off += c {
+			end := off + c
+			if end > la {
+				end = la
+			}
+			for i := copy(A.a, a[off:end]); i < n; i++ {
+				A.a[i] = 0
+			}
+			if _, err := A.fft(); err != nil {
+				return nil, err
+			}
+			if _, err := A.mulSample(B); err != nil {
+				return nil, err
+			}
+			if _, err := A.ifft(); err != nil {
+				return nil, err
+			}
+			for i := 0; i < end-off+lb-1; i++ {
+				R[off+i] ^= A.a[i]
+			}
 		}
-		if _, err := p.mulSample(q1); err != nil {
-			return nil, err
+		p.a = R
+		return p, nil
+	}
+	seg := make([]uint64, 2*lb-1)
+	t := make([]uint64, 4*lb+2*log2Ceil(lb))
+	A := make([]uint64, lb)
+	for off := 0; off < la; off += lb {
+		end := off + lb
+		if end > la {
+			end = la
 		}
-		if _, err := p.ifft(); err != nil {
-			return nil, err
+		for i := copy(A, a[off:end]); i < lb; i++ {
+			A[i] = 0
+		}
+		karatsuba(seg, A, b, t)
+		for i := 0; i < end-off+lb-1; i++ {
+			R[off+i] ^= seg[i]
 		}
 	}
+	p.a = R
 	return p, nil
 }
 
@@ -468,16 +874,16 @@ func (p *poly) invSample() (*poly, error) {
 		}
 	}
 
+	if j == 0 {
+		return p, nil
+	}
 	tB := make([]uint64, j)
 	tB[j-1] = inverse(tA[j-1])
 	j--
-	for i := n - 1; i >= 0; i-- {
+	for i := n - 1; i >= 0 && j > 0; i-- {
 		if p.a[i] != 0 {
 			tB[j-1] = mul64(p.a[i], tB[j])
 			j--
-			if j == 0 {
-				break
-			}
 		}
 	}
 
@@ -526,6 +932,101 @@ func (p *poly) div(q *poly) (*poly, error) {
 	return p, nil
 }
 
+// divMod divides p by q with schoolbook long division.
+// Quotient and remainder are returned as new polynomials,
+// neither p nor q is modified.
+func (p *poly) divMod(q *poly) (*poly, *poly, error) {
+	d := q.clone()
+	d.trimZeros()
+	ld := d.length()
+	if ld == 0 {
+		return nil, nil, errors.New("division by zero polynomial")
+	}
+	r := p.clone()
+	r.trimZeros()
+	lr := r.length()
+	if lr < ld {
+		return newPoly([]uint64{}), r, nil
+	}
+	quo := newEmptyPoly(lr - ld + 1)
+	lcInv := inverse(d.a[ld-1])
+	for i := lr - 1; i >= ld-1; i-- {
+		if r.a[i] == 0 {
+			continue
+		}
+		c := mul64(r.a[i], lcInv)
+		off := i - ld + 1
+		quo.a[off] = c
+		for j := 0; j < ld; j++ {
+			r.a[off+j] ^= mul64(c, d.a[j])
+		}
+	}
+	r.a = r.a[:ld-1]
+	r.trimZeros()
+	return quo, r, nil
+}
+
+func (p *poly) mod(q *poly) (*poly, error) {
+	_, r, err := p.divMod(q)
+	return r, err
+}
+
+// monic trims p and scales it so that leading coefficient is one.
+func (p *poly) monic() *poly {
+	p.trimZeros()
+	l := p.length()
+	if l == 0 || p.a[l-1] == 1 {
+		return p
+	}
+	lcInv := inverse(p.a[l-1])
+	for i := 0; i < l; i++ {
+		mulassign64(&p.a[i], lcInv)
+	}
+	return p
+}
+
+// gcd returns monic greatest common divisor of a and b.
+func gcd(a, b *poly) (*poly, error) {
+	r0, r1 := a.clone(), b.clone()
+	r0.trimZeros()
+	r1.trimZeros()
+	for r1.length() != 0 {
+		r, err := r0.mod(r1)
+		if err != nil {
+			return nil, err
+		}
+		r0, r1 = r1, r
+	}
+	return r0.monic(), nil
+}
+
+// mulMod returns p * q mod f as a new polynomial.
+func (p *poly) mulMod(q, f *poly) (*poly, error) {
+	if p.length() == 0 || q.length() == 0 {
+		return newPoly([]uint64{}), nil
+	}
+	r := p.clone()
+	if _, err := r.mul(q); err != nil {
+		return nil, err
+	}
+	return r.mod(f)
+}
+
+// squareMod returns p ^ 2 mod f as a new polynomial.
+// Squaring is linear in characteristic two so that
+// it only squares the coefficients and spreads them to even degrees.
+func (p *poly) squareMod(f *poly) (*poly, error) {
+	n := p.length()
+	if n == 0 {
+		return newPoly([]uint64{}), nil
+	}
+	r := newEmptyPoly(2*n - 1)
+	for i := 0; i < n; i++ {
+		r.a[2*i] = square64(p.a[i])
+	}
+	return r.mod(f)
+}
+
 func (p *poly) debug(desc string) {
 	fmt.Println(desc, len(p.a))
 	for i := 0; i < len(p.a); i++ {
@@ -533,27 +1034,79 @@ func (p *poly) debug(desc string) {
 	}
 }
 
+// zLeafSize is the number of roots up to which vanishing polynomial
+// is accumulated directly one linear factor at a time.
+const zLeafSize = 16
+
+// z returns vanishing polynomial of the given roots, prod (x - r_i).
+// If roots form a whole coset of a span of default bases,
+// vanishing polynomial is derived from the subspace polynomial.
+// Otherwise it is built with a product tree whose levels are split
+// with respect to the number of roots so that no padding is needed.
 func z(roots []uint64) (*poly, error) {
+	if k, ok := cosetDimension(roots); ok {
+		return newSubspacePoly(k).coset(roots[0]), nil
+	}
+	return zTree(roots)
+}
+
+func zTree(roots []uint64) (*poly, error) {
 	l := len(roots)
-	m := log2Ceil(l)
-	n := 1 << m
-	p := make([]poly, n)
-	for i := 0; i < l; i++ {
-		p[i] = poly{[]uint64{roots[i], uint64(1)}}
-	}
-	// fill with 1 * x ^ 0 + 0 * x ^ 1
-	// this is obviously suboptimal
-	// but let's leave it for a while in sake of simplicity
-	for i := l; i < n; i++ {
-		p[i] = poly{[]uint64{1, 0}}
-	}
-	for i := 0; i < m; i++ {
-		z := 1 << (m - i - 1)
-		for j := 0; j < z; j++ {
-			if _, err := p[j].mul(&p[j+z]); err != nil {
-				return nil, err
-			}
+	if l <= zLeafSize {
+		return zLeaf(roots), nil
+	}
+	h := l / 2
+	p, err := zTree(roots[:h])
+	if err != nil {
+		return nil, err
+	}
+	q, err := zTree(roots[h:])
+	if err != nil {
+		return nil, err
+	}
+	return p.mulMonic(q)
+}
+
+// mulMonic multiplies monic polynomials. Leading terms are
+// left out of the product such that (x^a + A)(x^b + B) is computed
+// as x^(a+b) + x^a * B + x^b * A + A * B, where A * B is shorter than the
+// product by one coefficient. Product tree levels then fit in FFT domains
+// of half size whenever number of roots is a power of two.
+func (p *poly) mulMonic(q *poly) (*poly, error) {
+	a, b := p.degree(), q.degree()
+	A := newPoly(p.a[:a])
+	B := newPoly(q.a[:b])
+	r := newEmptyPoly(a + b + 1)
+	r.a[a+b] = 1
+	for i := 0; i < b; i++ {
+		r.a[a+i] ^= B.a[i]
+	}
+	for i := 0; i < a; i++ {
+		r.a[b+i] ^= A.a[i]
+	}
+	if a == 0 || b == 0 {
+		return r, nil
+	}
+	AB := A.clone()
+	if _, err := AB.mul(B); err != nil {
+		return nil, err
+	}
+	for i := 0; i < AB.length(); i++ {
+		r.a[i] ^= AB.a[i]
+	}
+	return r, nil
+}
+
+// zLeaf multiplies linear factors in place one by one.
+func zLeaf(roots []uint64) *poly {
+	a := make([]uint64, len(roots)+1)
+	a[0] = 1
+	for i, r := range roots {
+		// (x + r) * (a_0 + ... + a_i x^i)
+		for j := i + 1; j > 0; j-- {
+			a[j] = a[j-1] ^ mul64(a[j], r)
 		}
+		a[0] = mul64(a[0], r)
 	}
-	return &p[0], nil
+	return newPoly(a)
 }
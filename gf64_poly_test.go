@@ -1,6 +1,7 @@
 package gf
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -52,6 +53,73 @@ func TestFFT(t *testing.T) {
 	}
 }
 
+func TestNovelBasis(t *testing.T) {
+	m := 6
+	n := 1 << m
+	initDefaultBasis(m)
+	// lfft evaluates X_i = prod ŝ_j^(b_j)
+	for i := 0; i < n; i++ {
+		e := newEmptyPoly(n)
+		e.a[i] = 1
+		if _, err := e.lfft(); err != nil {
+			t.Fatal(err)
+		}
+		for x := 0; x < n; x++ {
+			v := uint64(1)
+			for j := 0; j < m; j++ {
+				if i>>j&1 == 1 {
+					s := newSubspacePoly(j)
+					sj := mul64(s.eval(defaultBasis().combinations[x]), inverse(s.eval(defaultBasis().combinations[1<<j])))
+					v = mul64(v, sj)
+				}
+			}
+			if v != e.a[x] {
+				t.Fatal("novel basis polynomial evaluation failed", i, x)
+			}
+		}
+	}
+	f0 := randPoly(n)
+	f1 := f0.clone()
+	if _, err := f1.toNovelBasis(); err != nil {
+		t.Fatal(err)
+	}
+	f2 := f1.clone()
+	if _, err := f1.lfft(); err != nil {
+		t.Fatal(err)
+	}
+	e0 := f0.clone()
+	if _, err := e0.fft(); err != nil {
+		t.Fatal(err)
+	}
+	if !e0.equalInCoeff(f1) {
+		t.Fatal("evaluations in novel basis and monomial basis must match")
+	}
+	if _, err := f2.toMonomialBasis(); err != nil {
+		t.Fatal(err)
+	}
+	if !f2.equalInCoeff(f0) {
+		t.Fatal("conversion between bases failed")
+	}
+}
+
+func TestNovelDerivative(t *testing.T) {
+	initDefaultBasis(10)
+	f := randPoly(1 << 10)
+	d0 := f.derivative()
+	g := f.clone()
+	if _, err := g.toNovelBasis(); err != nil {
+		t.Fatal(err)
+	}
+	d1 := g.novelDerivative()
+	if _, err := d1.toMonomialBasis(); err != nil {
+		t.Fatal(err)
+	}
+	d1.trimZeros()
+	if d0.length() != d1.length() || !d0.equalInCoeff(d1) {
+		t.Fatal("derivative in novel basis failed")
+	}
+}
+
 func TestMulN(t *testing.T) {
 	A := randPoly(4)
 	B := randPoly(4)
@@ -149,6 +217,87 @@ func TestPolySubstitution(t *testing.T) {
 	}
 }
 
+func TestPolyDerivative(t *testing.T) {
+	f := newPoly([]uint64{1, 2, 3, 4, 5})
+	d := f.derivative()
+	if !d.equalInCoeff(newPoly([]uint64{2, 0, 4})) {
+		t.Fatal("derivative failed")
+	}
+	// (fg)' = f'g + fg'
+	for i := 0; i < 10; i++ {
+		f, g := randPoly(9), randPoly(14)
+		fg := f.clone()
+		fg.mulN(g)
+		df, dg := f.derivative(), g.derivative()
+		df.mulN(g)
+		dg.mulN(f)
+		df.expand(dg.length())
+		dg.expand(df.length())
+		df.add(dg)
+		df.trimZeros()
+		if !df.equalInCoeff(fg.derivative()) {
+			t.Fatal("product rule failed")
+		}
+	}
+	if newPoly([]uint64{7}).derivative().length() != 0 {
+		t.Fatal("derivative of a constant is zero")
+	}
+}
+
+func TestPolyComposition(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		f := randPoly(1 + i*7)
+		g := randPoly(1 + i)
+		h, err := f.compose(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.degree() != f.degree()*g.degree() {
+			t.Fatal("bad degree of composition")
+		}
+		for j := 0; j < 10; j++ {
+			x := randGF64()
+			if h.evalSingle(x) != f.evalSingle(g.evalSingle(x)) {
+				t.Fatal("composition failed")
+			}
+		}
+	}
+	f := randPoly(200)
+	g := randPoly(30)
+	m := randPoly(41)
+	h0, err := f.compose(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h0, err = h0.mod(m); err != nil {
+		t.Fatal(err)
+	}
+	h1, err := f.composeMod(g, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h0.length() != h1.length() || !h0.equalInCoeff(h1) {
+		t.Fatal("modular composition failed")
+	}
+}
+
+func TestPolyTaylorShift(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 64, 100} {
+		f := randPoly(n)
+		c := randGF64()
+		g := f.taylorShift(c)
+		if g.length() != f.length() {
+			t.Fatal("taylor shift must preserve length")
+		}
+		for j := 0; j < 10; j++ {
+			x := randGF64()
+			if g.evalSingle(x) != f.evalSingle(x^c) {
+				t.Fatal("taylor shift failed", n)
+			}
+		}
+	}
+}
+
 func TestPolyDiv(t *testing.T) {
 	initDefaultBasis(16)
 	for i := 0; i < 100; i++ {
@@ -275,6 +424,54 @@ func TestZPoly(t *testing.T) {
 	}
 }
 
+func TestZPolyProductTree(t *testing.T) {
+	initDefaultBasis(16)
+	for _, l := range []int{0, 1, 7, 16, 17, 100, 513} {
+		roots := randPoly(l).a
+		Z, err := z(roots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Z.length() != l+1 || Z.a[l] != 1 {
+			t.Fatal("vanishing polynomial expected to be monic with degree of number of roots", l)
+		}
+		for i := 0; i < l; i++ {
+			if Z.evalSingle(roots[i]) != 0 {
+				t.Fatalf("evaluation at root must be zero")
+			}
+		}
+	}
+}
+
+func TestZPolyCoset(t *testing.T) {
+	initDefaultBasis(16)
+	k := 6
+	// coset of span of first k bases is an aligned block of combinations
+	j := 5
+	roots := make([]uint64, 1<<k)
+	for i := 0; i < len(roots); i++ {
+		roots[i] = defaultBasis().combinations[j<<k+i]
+	}
+	if d, ok := cosetDimension(roots); !ok || d != k {
+		t.Fatal("aligned block expected to be detected as coset")
+	}
+	Z0, err := z(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Z1, err := zTree(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Z0.length() != Z1.length() || !Z0.equalInCoeff(Z1) {
+		t.Fatal("coset vanishing polynomial failed")
+	}
+	roots[3] = randGF64()
+	if _, ok := cosetDimension(roots); ok {
+		t.Fatal("broken coset must not be detected")
+	}
+}
+
 func BenchmarkRadixConversion(t *testing.B) {
 	m := polyLen
 	n := 1 << m
@@ -312,3 +509,102 @@ func BenchmarkZPoly(t *testing.B) {
 		}
 	}
 }
+
+func TestPolyMulKaratsuba(t *testing.T) {
+	for _, n := range []int{1, 5, 16, 17, 33, 100, 255} {
+		for _, l := range []int{1, n / 2, n} {
+			if l == 0 {
+				continue
+			}
+			A0 := randPoly(n)
+			B0 := randPoly(l)
+			A1 := A0.clone()
+			A0.mulN(B0)
+			A1.mulKaratsuba(B0)
+			if A0.length() != A1.length() || !A0.equalInCoeff(A1) {
+				t.Fatal("karatsuba multiplication failed", n, l)
+			}
+		}
+	}
+}
+
+func BenchmarkPolyMulCrossover(t *testing.B) {
+	initDefaultBasis(16)
+	for _, n := range []int{8, 16, 32, 64, 128, 256, 512} {
+		A := randPoly(n)
+		B := randPoly(n)
+		t.Run(fmt.Sprintf("schoolbook/%d", n), func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				A.clone().mulN(B)
+			}
+		})
+		t.Run(fmt.Sprintf("karatsuba/%d", n), func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				A.clone().mulKaratsuba(B)
+			}
+		})
+		t.Run(fmt.Sprintf("fft/%d", n), func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				if _, err := A.clone().mulFFT(B); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestPolyMulUnbalanced(t *testing.T) {
+	initDefaultBasis(16)
+	sizes := [][2]int{{5, 300}, {300, 5}, {20, 1000}, {300, 520}, {520, 300}, {256, 4000}, {4000, 300}, {1000, 1000}}
+	for _, s := range sizes {
+		A0 := randPoly(s[0])
+		B0 := randPoly(s[1])
+		A1 := A0.clone()
+		A0.mulN(B0)
+		if _, err := A1.mul(B0); err != nil {
+			t.Fatal(err)
+		}
+		if A1.length() != s[0]+s[1]-1 {
+			t.Fatal("bad product length", s)
+		}
+		if !A0.equalInCoeff(A1) {
+			t.Fatal("unbalanced multiplication failed", s)
+		}
+	}
+}
+
+func BenchmarkPolyMulUnbalanced(t *testing.B) {
+	initDefaultBasis(16)
+	A := randPoly(1 << 12)
+	B := randPoly(1 << 8)
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if _, err := A.clone().mul(B); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkZPolyUnaligned(t *testing.B) {
+	m := polyLen
+	initDefaultBasis(m)
+	roots := randPoly(1<<(m-1) + 1)
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if _, err := z(roots.a); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkZPolyCoset(t *testing.B) {
+	m := polyLen
+	initDefaultBasis(m)
+	roots := defaultBasis().combinations[1<<(m-1):]
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if _, err := z(roots); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
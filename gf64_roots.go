@@ -0,0 +1,167 @@
+package gf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// rootsInSpan finds roots of the polynomial that lie in the span of
+// first m default bases. Polynomial is evaluated at all points of the span
+// with a single additive FFT as in McBits, so that it is the method of choice
+// when the domain is small. Length of the polynomial must not exceed 2^m.
+// Distinct roots are returned in the order of basis combinations
+// alongside with their multiplicities.
+func (p *poly) rootsInSpan(m int) ([]uint64, []int, error) {
+	f := p.clone()
+	f.trimZeros()
+	if f.length() == 0 {
+		return nil, nil, errors.New("zero polynomial vanishes at every point")
+	}
+	if m < 1 {
+		return nil, nil, fmt.Errorf("span dimension expected to be positive: %d", m)
+	}
+	n := 1 << m
+	if !fftFits(n) {
+		return nil, nil, fmt.Errorf("default basis is not large enough for span size %d", n)
+	}
+	if f.length() > n {
+		return nil, nil, fmt.Errorf("polynomial length %d exceeds span size %d", f.length(), n)
+	}
+	f.expand(n)
+	if _, err := f.fft(); err != nil {
+		return nil, nil, err
+	}
+	roots := []uint64{}
+	for i := 0; i < n; i++ {
+		if f.a[i] == 0 {
+			roots = append(roots, defaultBasis().combinations[i])
+		}
+	}
+	return roots, p.multiplicities(roots), nil
+}
+
+// roots finds all roots of the polynomial in GF(2^64) with Berlekamp trace
+// algorithm. Product of distinct linear factors is first extracted as
+// gcd(f, x^(2^64) - x) and then it is split recursively with gcd(g, Tr(βx) mod g)
+// where β runs over the polynomial basis of the field.
+// Distinct roots are returned in ascending order
+// alongside with their multiplicities.
+func (p *poly) roots() ([]uint64, []int, error) {
+	f := p.clone().monic()
+	if f.length() == 0 {
+		return nil, nil, errors.New("zero polynomial vanishes at every point")
+	}
+	if f.length() == 1 {
+		return []uint64{}, []int{}, nil
+	}
+	// x ^ (2 ^ 64) mod f
+	xq, err := newPoly([]uint64{0, 1}).mod(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 0; i < 64; i++ {
+		if xq, err = xq.squareMod(f); err != nil {
+			return nil, nil, err
+		}
+	}
+	// x ^ (2 ^ 64) - x mod f
+	xq.expand(2)
+	xq.a[1] ^= 1
+	xq.trimZeros()
+	g, err := gcd(f, xq)
+	if err != nil {
+		return nil, nil, err
+	}
+	roots, err := splitTrace(g, []uint64{})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	return roots, p.multiplicities(roots), nil
+}
+
+// splitTrace appends roots of g to the given list.
+// g is expected to be monic and product of distinct linear factors.
+func splitTrace(g *poly, roots []uint64) ([]uint64, error) {
+	switch g.degree() {
+	case 0:
+		return roots, nil
+	case 1:
+		// x + a
+		return append(roots, g.a[0]), nil
+	}
+	// Trace form is non degenerate so that any two distinct roots
+	// are separated by the trace of one of the polynomial basis elements.
+	for i := 0; i < 64; i++ {
+		t, err := traceMod(uint64(1)<<i, g)
+		if err != nil {
+			return nil, err
+		}
+		d, err := gcd(g, t)
+		if err != nil {
+			return nil, err
+		}
+		if d.degree() < 1 || d.degree() == g.degree() {
+			continue
+		}
+		h, _, err := g.divMod(d)
+		if err != nil {
+			return nil, err
+		}
+		if roots, err = splitTrace(d, roots); err != nil {
+			return nil, err
+		}
+		return splitTrace(h.monic(), roots)
+	}
+	return nil, errors.New("polynomial is not a product of distinct linear factors")
+}
+
+// traceMod computes Tr(βx) = βx + (βx)^2 + ... + (βx)^(2^63) mod g.
+func traceMod(beta uint64, g *poly) (*poly, error) {
+	u, err := newPoly([]uint64{0, beta}).mod(g)
+	if err != nil {
+		return nil, err
+	}
+	acc := u.clone()
+	for i := 1; i < 64; i++ {
+		if u, err = u.squareMod(g); err != nil {
+			return nil, err
+		}
+		acc.expand(u.length())
+		acc.add(u)
+	}
+	acc.trimZeros()
+	return acc, nil
+}
+
+func (p *poly) multiplicities(roots []uint64) []int {
+	mult := make([]int, len(roots))
+	for i := 0; i < len(roots); i++ {
+		mult[i] = p.rootMultiplicity(roots[i])
+	}
+	return mult
+}
+
+// rootMultiplicity counts how many times p is divisible by x - r
+// with repeated synthetic division.
+func (p *poly) rootMultiplicity(r uint64) int {
+	f := p.clone()
+	f.trimZeros()
+	k := 0
+	for f.length() > 1 {
+		n := f.length()
+		q := make([]uint64, n-1)
+		acc := f.a[n-1]
+		for i := n - 2; i >= 0; i-- {
+			q[i] = acc
+			acc = mul64(acc, r) ^ f.a[i]
+		}
+		if acc != 0 {
+			break
+		}
+		f = newPoly(q)
+		k++
+	}
+	return k
+}
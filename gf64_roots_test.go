@@ -0,0 +1,128 @@
+package gf
+
+import (
+	"sort"
+	"testing"
+)
+
+// polyFromRoots builds prod (x - roots[i]) ^ mult[i]
+func polyFromRoots(roots []uint64, mult []int) *poly {
+	f := newPoly([]uint64{1})
+	for i := 0; i < len(roots); i++ {
+		for j := 0; j < mult[i]; j++ {
+			f.mulN(newPoly([]uint64{roots[i], 1}))
+		}
+	}
+	return f
+}
+
+func TestRootsInSpan(t *testing.T) {
+	m := 8
+	initDefaultBasis(m)
+	roots := []uint64{
+		defaultBasis().combinations[0],
+		defaultBasis().combinations[3],
+		defaultBasis().combinations[100],
+		defaultBasis().combinations[255],
+	}
+	mult := []int{1, 3, 2, 1}
+	f := polyFromRoots(roots, mult)
+	// a root outside of the span
+	f.mulN(newPoly([]uint64{randGF64(), 1}))
+	r, k, err := f.rootsInSpan(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != len(roots) {
+		t.Fatalf("expected %d roots, got %d", len(roots), len(r))
+	}
+	for i := 0; i < len(roots); i++ {
+		if r[i] != roots[i] || k[i] != mult[i] {
+			t.Fatal("root finding in span failed", i)
+		}
+	}
+	if _, _, err := randPoly(1<<m + 1).rootsInSpan(m); err == nil {
+		t.Fatal("polynomial longer than span must be rejected")
+	}
+}
+
+func TestRoots(t *testing.T) {
+	initDefaultBasis(8)
+	for i := 0; i < 10; i++ {
+		roots := randPoly(6).a
+		mult := []int{1, 2, 1, 3, 1, 1}
+		f := polyFromRoots(roots, mult)
+		// an irreducible quadratic factor x^2 + x + c with Tr(c) = 1
+		c := randGF64()
+		for trace(c) != 1 {
+			c = randGF64()
+		}
+		f.mulN(newPoly([]uint64{c, 1, 1}))
+		// scale to a non monic polynomial
+		f.mulN(newPoly([]uint64{randGF64()}))
+		r, k, err := f.roots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r) != len(roots) {
+			t.Fatalf("expected %d roots, got %d", len(roots), len(r))
+		}
+		expected := map[uint64]int{}
+		for j := 0; j < len(roots); j++ {
+			expected[roots[j]] = mult[j]
+		}
+		if !sort.SliceIsSorted(r, func(i, j int) bool { return r[i] < r[j] }) {
+			t.Fatal("roots are expected to be sorted")
+		}
+		for j := 0; j < len(r); j++ {
+			if expected[r[j]] != k[j] {
+				t.Fatal("root finding failed", j)
+			}
+		}
+	}
+	r, _, err := newPoly([]uint64{randGF64()}).roots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 0 {
+		t.Fatal("constant polynomial has no roots")
+	}
+	if _, _, err := newPoly([]uint64{0, 0}).roots(); err == nil {
+		t.Fatal("zero polynomial must be rejected")
+	}
+}
+
+func TestPolyDivMod(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		a := randPoly(40)
+		b := randPoly(13)
+		q, r, err := a.divMod(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.length() >= b.length() {
+			t.Fatal("remainder degree is too high")
+		}
+		q.mulN(b)
+		q.expand(a.length())
+		q.add(r)
+		if !q.equalInCoeff(a) {
+			t.Fatal("a = q * b + r")
+		}
+	}
+}
+
+func BenchmarkRoots(t *testing.B) {
+	initDefaultBasis(8)
+	mult := make([]int, 64)
+	for i := 0; i < len(mult); i++ {
+		mult[i] = 1
+	}
+	f := polyFromRoots(randPoly(64).a, mult)
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if _, _, err := f.roots(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package gf
+
+// subspacePoly is the subspace polynomial s_k(x) = prod (x - w)
+// where w runs over W_k which is span of first k default bases.
+// Subspace polynomials are linearized, s_k(x) = sum c_j x^(2^j),
+// so that only k + 1 coefficients are kept.
+type subspacePoly struct {
+	k int
+	c []uint64
+}
+
+// newSubspacePoly generates s_k following GM10 recursion
+// s_(i+1)(x) = s_i(x)^2 + s_i(β_i) s_i(x) in O(k^2) operations.
+func newSubspacePoly(k int) *subspacePoly {
+	c := make([]uint64, k+1)
+	c[0] = 1
+	s := &subspacePoly{0, c[:1]}
+	for i := 0; i < k; i++ {
+		v := s.eval(defaultBasis().combinations[1<<i])
+		for j := i + 1; j > 0; j-- {
+			c[j] = square64(c[j-1]) ^ mul64(v, c[j])
+		}
+		mulassign64(&c[0], v)
+		s = &subspacePoly{i + 1, c[:i+2]}
+	}
+	return s
+}
+
+// eval evaluates s_k at a point with k squarings.
+func (s *subspacePoly) eval(x uint64) uint64 {
+	var acc uint64
+	for j := 0; j <= s.k; j++ {
+		acc ^= mul64(s.c[j], x)
+		squareassign64(&x)
+	}
+	return acc
+}
+
+// frobenius returns dense form of s_k(x)^(2^i) which is
+// also linearized with coefficients c_j^(2^i) at degrees 2^(i+j).
+func (s *subspacePoly) frobenius(i int) *poly {
+	p := newEmptyPoly(1<<(s.k+i) + 1)
+	for j := 0; j <= s.k; j++ {
+		c := s.c[j]
+		for t := 0; t < i; t++ {
+			squareassign64(&c)
+		}
+		p.a[1<<(j+i)] = c
+	}
+	return p
+}
+
+// dense returns s_k in coefficient form.
+func (s *subspacePoly) dense() *poly {
+	return s.frobenius(0)
+}
+
+// coset returns vanishing polynomial of the coset v + W_k
+// which is s_k(x) - s_k(v).
+func (s *subspacePoly) coset(v uint64) *poly {
+	p := s.dense()
+	p.a[0] = s.eval(v)
+	return p
+}
+
+// cosets returns vanishing polynomial of the union of distinct cosets
+// v_i + W_k. It is Q(s_k(x)) where Q(y) = prod (y - s_k(v_i)),
+// so that only a polynomial of degree of the number of cosets is built
+// with product tree. Powers of s_k needed for composition are derived
+// with Frobenius map rather than multiplication.
+func (s *subspacePoly) cosets(vs []uint64) (*poly, error) {
+	ys := make([]uint64, len(vs))
+	for i := 0; i < len(vs); i++ {
+		ys[i] = s.eval(vs[i])
+	}
+	Q, err := zTree(ys)
+	if err != nil {
+		return nil, err
+	}
+	m := log2Ceil(Q.length())
+	pows := make([]*poly, m+1)
+	for i := 0; i <= m; i++ {
+		pows[i] = s.frobenius(i)
+	}
+	return Q.composeRec(pows, m)
+}
+
+// cosetDimension checks if the given points form a whole coset v + W_k
+// and returns k if so.
+func cosetDimension(points []uint64) (int, bool) {
+	l := len(points)
+	if l < 2 || l&(l-1) != 0 {
+		return 0, false
+	}
+	k := log2Floor(l)
+	if b := defaultBasis(); b == nil || b.m < k {
+		return 0, false
+	}
+	s := newSubspacePoly(k)
+	// distinct points vanishing s_k(x - v) fill the coset
+	v := points[0]
+	seen := make(map[uint64]bool, l)
+	for _, r := range points {
+		if seen[r] || s.eval(r^v) != 0 {
+			return 0, false
+		}
+		seen[r] = true
+	}
+	return k, true
+}
+
+// alignedBlockDimension returns the largest k such that indices
+// are union of aligned blocks of size 2^k. Such a block of indices
+// is mapped to a coset of W_k in basis combinations.
+func alignedBlockDimension(indices []uint64) int {
+	set := make(map[uint64]bool, len(indices))
+	for _, i := range indices {
+		set[i] = true
+	}
+	if len(set) == 0 {
+		return 0
+	}
+	for k := 0; k < 63; k++ {
+		bit := uint64(1) << k
+		for i := range set {
+			if !set[i^bit] {
+				return k
+			}
+		}
+	}
+	return 63
+}
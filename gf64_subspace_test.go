@@ -0,0 +1,73 @@
+package gf
+
+import (
+	"testing"
+)
+
+func TestSubspacePoly(t *testing.T) {
+	initDefaultBasis(12)
+	for k := 0; k <= 10; k++ {
+		s := newSubspacePoly(k)
+		for i := 0; i < 1<<k; i++ {
+			if s.eval(defaultBasis().combinations[i]) != 0 {
+				t.Fatal("subspace polynomial must vanish at the span", k, i)
+			}
+		}
+		if k < 12 && s.eval(defaultBasis().combinations[1<<k]) == 0 {
+			t.Fatal("subspace polynomial must not vanish out of the span", k)
+		}
+		// linearity
+		a, b := randGF64(), randGF64()
+		if s.eval(a^b) != s.eval(a)^s.eval(b) {
+			t.Fatal("subspace polynomial is expected to be linear")
+		}
+		Z, err := zTree(defaultBasis().combinations[:1<<k])
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := s.dense()
+		if d.length() != Z.length() || !d.equalInCoeff(Z) {
+			t.Fatal("dense subspace polynomial failed", k)
+		}
+		x := randGF64()
+		if d.evalSingle(x) != s.eval(x) {
+			t.Fatal("evaluation of subspace polynomial failed", k)
+		}
+	}
+}
+
+func TestSubspacePolyCosets(t *testing.T) {
+	initDefaultBasis(12)
+	k := 4
+	s := newSubspacePoly(k)
+	blocks := []int{1, 7, 8, 30, 255}
+	reps := []uint64{}
+	roots := []uint64{}
+	for _, b := range blocks {
+		reps = append(reps, defaultBasis().combinations[b<<k])
+		roots = append(roots, defaultBasis().combinations[b<<k:(b+1)<<k]...)
+	}
+	Z0, err := s.cosets(reps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Z1, err := zTree(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Z0.length() != Z1.length() || !Z0.equalInCoeff(Z1) {
+		t.Fatal("vanishing polynomial of cosets failed")
+	}
+}
+
+func TestAlignedBlockDimension(t *testing.T) {
+	if k := alignedBlockDimension([]uint64{0, 1, 2, 3, 8, 9, 10, 11}); k != 2 {
+		t.Fatal("expected blocks of size 4", k)
+	}
+	if k := alignedBlockDimension([]uint64{1, 2, 3, 4}); k != 0 {
+		t.Fatal("unaligned indices", k)
+	}
+	if k := alignedBlockDimension([]uint64{6, 7}); k != 1 {
+		t.Fatal("expected block of size 2", k)
+	}
+}
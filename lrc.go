@@ -0,0 +1,318 @@
+package gf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// LRC is a local reconstruction code in the style of Azure storage with
+// k data shards split into l local groups, one XOR parity per group and
+// r global parities. Shards are laid out as data shards, local parities
+// and global parities.
+//
+// Global parities are built as a pyramid code on a systematic codeword of
+// a Reed-Solomon codec of dimension k. Data shard p is symbol p of the
+// codeword scaled by 1 / w_p, where w_p is weight of symbol p in symbol k
+// as a combination of the first k symbols, so that symbol k is the XOR of
+// data shards. Symbol k is split into local parities of groups and symbols
+// k + 1, ..., k + r are global parities.
+//
+// As the codec is MDS, any k of symbols 0, ..., k + r recover the rest.
+// Symbol k is known while all local parities are, and a local parity that
+// is not repaired locally is lost together with a data shard of its group,
+// so that any r + 1 losses leave at most r + 1 symbols unknown. Some
+// patterns of more losses are recoverable only with local parities taken
+// separately and are not recovered.
+//
+// A single loss in a group is repaired reading the rest of the group and
+// other patterns are recovered with the codec from available shards.
+type LRC struct {
+	k, l, r int
+	c       *Codec
+	// w[p] is weight of data symbol p in symbol k and scale[p] is its inverse
+	w, scale []uint64
+}
+
+// NewLRC returns a local reconstruction code. k is expected to be divisible
+// by l and k + r + 1 is bounded by MaxShards of the codec.
+func NewLRC(k, l, r int) (*LRC, error) {
+	if k < 1 || l < 1 || r < 0 || k%l != 0 {
+		return nil, fmt.Errorf("bad code parameters k = %d, l = %d, r = %d", k, l, r)
+	}
+	if k+r+1 > MaxShards {
+		return nil, fmt.Errorf("codeword of %d symbols exceeds %d", k+r+1, MaxShards)
+	}
+	n := 2
+	for n < k+r+1 {
+		n <<= 1
+	}
+	codec, err := NewCodec(n, k)
+	if err != nil {
+		return nil, err
+	}
+	from := make([]int, k)
+	for p := range from {
+		from[p] = p
+	}
+	weights, err := codec.interpolationWeights(from, []int{k})
+	if err != nil {
+		return nil, err
+	}
+	c := &LRC{k: k, l: l, r: r, c: codec, w: weights[0]}
+	scale := newPoly(append([]uint64{}, c.w...))
+	if _, err := scale.invSample(); err != nil {
+		return nil, err
+	}
+	c.scale = scale.a
+	return c, nil
+}
+
+// Shards returns total number of shards.
+func (c *LRC) Shards() int {
+	return c.k + c.l + c.r
+}
+
+// groupSize returns number of data shards in a local group.
+func (c *LRC) groupSize() int {
+	return c.k / c.l
+}
+
+// group returns shard indices of local group g, data shards followed by the local parity.
+func (c *LRC) group(g int) []int {
+	s := c.groupSize()
+	members := make([]int, 0, s+1)
+	for p := g * s; p < (g+1)*s; p++ {
+		members = append(members, p)
+	}
+	return append(members, c.k+g)
+}
+
+// localParity computes local parity of group g from data shards.
+func (c *LRC) localParity(shards [][]uint64, g int) []uint64 {
+	out := make([]uint64, len(shards[0]))
+	for _, p := range c.group(g) {
+		if p < c.k {
+			for j := range out {
+				out[j] ^= shards[p][j]
+			}
+		}
+	}
+	return out
+}
+
+// scaled returns a copy of shard multiplied by f.
+func scaled(shard []uint64, f uint64) []uint64 {
+	out := make([]uint64, len(shard))
+	for j := range out {
+		out[j] = mul64(f, shard[j])
+	}
+	return out
+}
+
+// global fills codec symbols at wanted in place from available ones,
+// symbols[i] being symbol i of the codec or nil if it is not available.
+// It returns indices of symbols that are read.
+func (c *LRC) global(symbols [][]uint64, wanted []int) ([]int, error) {
+	available := 0
+	for _, s := range symbols {
+		if s != nil {
+			available++
+		}
+	}
+	if available < c.k {
+		return nil, errors.New("failure pattern is not recoverable")
+	}
+	return c.c.reconstructSome(symbols, wanted)
+}
+
+func (c *LRC) checkShards(shards [][]uint64) (int, error) {
+	if len(shards) != c.Shards() {
+		return 0, fmt.Errorf("expected %d shards, got %d", c.Shards(), len(shards))
+	}
+	size := -1
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if size != -1 && len(shard) != size {
+			return 0, fmt.Errorf("shard %d length %d is expected to be %d", i, len(shard), size)
+		}
+		size = len(shard)
+	}
+	if size == -1 {
+		return 0, errors.New("no shard is given")
+	}
+	return size, nil
+}
+
+// Encode computes parity shards in place. First k shards are data shards
+// of equal length and parity shards are allocated.
+func (c *LRC) Encode(shards [][]uint64) error {
+	if _, err := c.checkShards(shards); err != nil {
+		return err
+	}
+	for p := 0; p < c.k; p++ {
+		if shards[p] == nil {
+			return fmt.Errorf("data shard %d is missing", p)
+		}
+	}
+	for g := 0; g < c.l; g++ {
+		shards[c.k+g] = c.localParity(shards, g)
+	}
+	if c.r == 0 {
+		return nil
+	}
+	symbols := make([][]uint64, c.c.N())
+	for p := 0; p < c.k; p++ {
+		symbols[p] = scaled(shards[p], c.scale[p])
+	}
+	wanted := make([]int, c.r)
+	for e := range wanted {
+		wanted[e] = c.k + 1 + e
+	}
+	if _, err := c.global(symbols, wanted); err != nil {
+		return err
+	}
+	for e, i := range wanted {
+		shards[c.k+c.l+e] = symbols[i]
+	}
+	return nil
+}
+
+// RepairCost reports the cost of a reconstruction.
+type RepairCost struct {
+	// Read lists shards that are read.
+	Read []int
+	// Local is true if missing shards are repaired within their local groups.
+	Local bool
+}
+
+// Reconstruct fills missing shards, given as nil, in place
+// and reports which shards are read.
+func (c *LRC) Reconstruct(shards [][]uint64) (*RepairCost, error) {
+	size, err := c.checkShards(shards)
+	if err != nil {
+		return nil, err
+	}
+	present := make([]bool, len(shards))
+	for i := range shards {
+		present[i] = shards[i] != nil
+	}
+	read := map[int]bool{}
+	local := true
+
+	// a single loss in a group is the XOR of the rest of the group
+	for g := 0; g < c.l; g++ {
+		members := c.group(g)
+		lost := -1
+		for _, i := range members {
+			if shards[i] == nil {
+				if lost != -1 {
+					lost = -2
+					break
+				}
+				lost = i
+			}
+		}
+		if lost < 0 {
+			continue
+		}
+		out := make([]uint64, size)
+		for _, i := range members {
+			if i == lost {
+				continue
+			}
+			read[i] = true
+			for j := 0; j < size; j++ {
+				out[j] ^= shards[i][j]
+			}
+		}
+		shards[lost] = out
+	}
+
+	// other losses are recovered with the codec, reading only data
+	// shards when they are all available
+	symbols := make([][]uint64, c.c.N())
+	wanted := []int{}
+	data := true
+	for p := 0; p < c.k; p++ {
+		if shards[p] == nil {
+			wanted = append(wanted, p)
+			data = false
+			continue
+		}
+		symbols[p] = scaled(shards[p], c.scale[p])
+	}
+	sum := !data
+	for g := 0; g < c.l && sum; g++ {
+		sum = shards[c.k+g] != nil
+	}
+	if sum {
+		symbols[c.k] = make([]uint64, size)
+		for g := 0; g < c.l; g++ {
+			for j := 0; j < size; j++ {
+				symbols[c.k][j] ^= shards[c.k+g][j]
+			}
+		}
+	}
+	for e := 0; e < c.r; e++ {
+		i := c.k + c.l + e
+		if shards[i] == nil {
+			wanted = append(wanted, c.k+1+e)
+		} else if !data {
+			symbols[c.k+1+e] = shards[i]
+		}
+	}
+	if len(wanted) != 0 {
+		local = false
+		from, err := c.global(symbols, wanted)
+		if err != nil {
+			return nil, err
+		}
+		// symbol k is the sum of local parities
+		for _, i := range from {
+			switch {
+			case i < c.k:
+				read[i] = true
+			case i == c.k:
+				for g := 0; g < c.l; g++ {
+					read[c.k+g] = true
+				}
+			default:
+				read[c.k+c.l+(i-c.k-1)] = true
+			}
+		}
+		for _, i := range wanted {
+			if i < c.k {
+				shards[i] = scaled(symbols[i], c.w[i])
+			} else {
+				shards[c.k+c.l+(i-c.k-1)] = symbols[i]
+			}
+		}
+	}
+
+	// remaining local parities are computed from data shards
+	for g := 0; g < c.l; g++ {
+		if shards[c.k+g] != nil {
+			continue
+		}
+		local = false
+		for _, p := range c.group(g) {
+			if p < c.k {
+				read[p] = true
+			}
+		}
+		shards[c.k+g] = c.localParity(shards, g)
+	}
+
+	cost := &RepairCost{Local: local}
+	// reconstructed shards are reused from memory
+	for i := range read {
+		if present[i] {
+			cost.Read = append(cost.Read, i)
+		}
+	}
+	sort.Ints(cost.Read)
+	return cost, nil
+}
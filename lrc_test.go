@@ -0,0 +1,200 @@
+package gf
+
+import (
+	"math/bits"
+	"reflect"
+	"testing"
+)
+
+func lrcShards(t *testing.T, c *LRC, size int) [][]uint64 {
+	shards := make([][]uint64, c.Shards())
+	for p := 0; p < c.k; p++ {
+		shards[p] = randPoly(size).a
+	}
+	if err := c.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	return shards
+}
+
+// lrcRecoverable checks if data is determined by shards out of erased mask,
+// that is columns of present shards in generator matrix have rank k.
+// Generator matrix is read from encodings of unit data vectors.
+func lrcRecoverable(c *LRC, erased uint) bool {
+	units := make([][][]uint64, c.k)
+	for p := range units {
+		units[p] = make([][]uint64, c.Shards())
+		for q := 0; q < c.k; q++ {
+			units[p][q] = []uint64{0}
+		}
+		units[p][p][0] = 1
+		if err := c.Encode(units[p]); err != nil {
+			panic(err)
+		}
+	}
+	cols := [][]uint64{}
+	for i := 0; i < c.Shards(); i++ {
+		if erased&(1<<i) != 0 {
+			continue
+		}
+		col := make([]uint64, c.k)
+		for p := range col {
+			col[p] = units[p][i][0]
+		}
+		cols = append(cols, col)
+	}
+	rank := 0
+	for row := 0; row < c.k && rank < len(cols); row++ {
+		pivot := -1
+		for i := rank; i < len(cols); i++ {
+			if cols[i][row] != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot == -1 {
+			continue
+		}
+		cols[rank], cols[pivot] = cols[pivot], cols[rank]
+		f := inverse(cols[rank][row])
+		for i := 0; i < len(cols); i++ {
+			if i == rank || cols[i][row] == 0 {
+				continue
+			}
+			g := mul64(cols[i][row], f)
+			for t := 0; t < c.k; t++ {
+				cols[i][t] ^= mul64(g, cols[rank][t])
+			}
+		}
+		rank++
+	}
+	return rank == c.k
+}
+
+func TestLRCAllPatterns(t *testing.T) {
+	for _, p := range [][3]int{{4, 2, 2}, {6, 2, 2}, {6, 3, 1}, {4, 1, 2}, {8, 2, 3}, {6, 3, 3}} {
+		c, err := NewLRC(p[0], p[1], p[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := lrcShards(t, c, 3)
+		for erased := uint(0); erased < 1<<c.Shards(); erased++ {
+			shards := append([][]uint64{}, original...)
+			for i := range shards {
+				if erased&(1<<i) != 0 {
+					shards[i] = nil
+				}
+			}
+			recoverable := lrcRecoverable(c, erased)
+			failures := bits.OnesCount(erased)
+			if failures <= c.r+1 && !recoverable {
+				t.Fatal("any r + 1 failures must be recoverable", p, erased)
+			}
+			cost, err := c.Reconstruct(shards)
+			if !recoverable {
+				if err == nil {
+					t.Fatal("unrecoverable pattern must fail", p, erased)
+				}
+				continue
+			}
+			// beyond r + 1 failures, local parities of groups with several
+			// losses are combined into a single codec symbol, which may
+			// leave a recoverable pattern to the codec with too few symbols
+			if err != nil {
+				if failures <= c.r+1 {
+					t.Fatal(p, erased, err)
+				}
+				continue
+			}
+			for i := range shards {
+				for j := range shards[i] {
+					if shards[i][j] != original[i][j] {
+						t.Fatal("bad reconstruction", p, erased, i)
+					}
+				}
+			}
+			for _, i := range cost.Read {
+				if erased&(1<<i) != 0 {
+					t.Fatal("missing shard can not be read", p, erased, i)
+				}
+			}
+		}
+	}
+}
+
+func TestLRCParities(t *testing.T) {
+	c, err := NewLRC(6, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := lrcShards(t, c, 4)
+	// scaled data and global parities are symbols of a codeword whose
+	// symbol k is the XOR of data, here reconstructed without data shards 0, 1, 2
+	symbols := make([][]uint64, c.c.N())
+	for p := c.r; p < c.k; p++ {
+		symbols[p] = scaled(shards[p], c.scale[p])
+	}
+	for e := 0; e < c.r; e++ {
+		symbols[c.k+1+e] = shards[c.k+c.l+e]
+	}
+	if err := c.c.ReconstructSome(symbols, []int{c.k}); err != nil {
+		t.Fatal(err)
+	}
+	for j := range shards[0] {
+		sum := shards[c.k][j] ^ shards[c.k+1][j]
+		for p := 0; p < c.k; p++ {
+			sum ^= shards[p][j]
+		}
+		if sum != 0 {
+			t.Fatal("local parities must sum to XOR of data")
+		}
+		if symbols[c.k][j] != shards[c.k][j]^shards[c.k+1][j] {
+			t.Fatal("global parities must be symbols of the codeword")
+		}
+	}
+	if _, err := NewLRC(MaxShards, 2, 1); err == nil {
+		t.Fatal("codeword longer than codec must be rejected")
+	}
+}
+
+func TestLRCLocalRepairCost(t *testing.T) {
+	c, err := NewLRC(12, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := lrcShards(t, c, 8)
+	for _, lost := range []int{0, 5, 11, 13} {
+		shards := append([][]uint64{}, original...)
+		shards[lost] = nil
+		cost, err := c.Reconstruct(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cost.Local || len(cost.Read) != c.groupSize() {
+			t.Fatal("single loss must be repaired reading its group", lost, cost.Read)
+		}
+	}
+	// a lost global parity is computed from all data shards
+	shards := append([][]uint64{}, original...)
+	shards[16] = nil
+	cost, err := c.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost.Local || !reflect.DeepEqual(cost.Read, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}) {
+		t.Fatal("global parity must be repaired reading data shards", cost.Read)
+	}
+	// two losses in a group need global parities
+	shards = append([][]uint64{}, original...)
+	shards[0], shards[1] = nil, nil
+	cost, err = c.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// k symbols of the codec are read, local parities taken as their sum
+	// and the last global parity is not needed
+	expected := []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	if cost.Local || !reflect.DeepEqual(cost.Read, expected) {
+		t.Fatal("two losses in a group must be repaired with global parities", cost.Read)
+	}
+}
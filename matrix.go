@@ -0,0 +1,47 @@
+package gf
+
+import (
+	"errors"
+)
+
+// invertMatrix inverts a square matrix with Gauss-Jordan elimination.
+func invertMatrix(m [][]uint64) ([][]uint64, error) {
+	n := len(m)
+	a := make([][]uint64, n)
+	inv := make([][]uint64, n)
+	for i := 0; i < n; i++ {
+		a[i] = append([]uint64{}, m[i]...)
+		inv[i] = make([]uint64, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for i := col; i < n; i++ {
+			if a[i][col] != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot == -1 {
+			return nil, errors.New("matrix is singular")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		f := inverse(a[col][col])
+		for t := 0; t < n; t++ {
+			mulassign64(&a[col][t], f)
+			mulassign64(&inv[col][t], f)
+		}
+		for i := 0; i < n; i++ {
+			if i == col || a[i][col] == 0 {
+				continue
+			}
+			f := a[i][col]
+			for t := 0; t < n; t++ {
+				a[i][t] ^= mul64(f, a[col][t])
+				inv[i][t] ^= mul64(f, inv[col][t])
+			}
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,259 @@
+package gf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// Merkle trees commit to symbols of a codeword or to shards, leaf i being
+// symbol i or shard i of the RS codec. Leaves and inner nodes are hashed
+// with distinct prefixes so that a leaf can not be taken for a node.
+// Number of leaves is padded to a power of two with empty nodes, which are
+// all zero digests.
+
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleTree is a binary Merkle tree.
+type MerkleTree struct {
+	leaves int
+	// layers[0] are leaf hashes and last layer is the root
+	layers [][][]byte
+}
+
+// NewMerkleTree builds a tree over leaves with hash function h, SHA-256 if nil.
+func NewMerkleTree(leaves [][]byte, h func() hash.Hash) (*MerkleTree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("no leaves to commit")
+	}
+	if h == nil {
+		h = sha256.New
+	}
+	size := 1 << log2Ceil(len(leaves))
+	empty := make([]byte, h().Size())
+	layer := make([][]byte, size)
+	for i := range layer {
+		if i < len(leaves) {
+			layer[i] = hashLeaf(h, leaves[i])
+		} else {
+			layer[i] = empty
+		}
+	}
+	t := &MerkleTree{leaves: len(leaves), layers: [][][]byte{layer}}
+	for len(layer) > 1 {
+		next := make([][]byte, len(layer)/2)
+		for i := range next {
+			next[i] = hashNode(h, layer[2*i], layer[2*i+1])
+		}
+		t.layers = append(t.layers, next)
+		layer = next
+	}
+	return t, nil
+}
+
+func hashLeaf(h func() hash.Hash, leaf []byte) []byte {
+	d := h()
+	d.Write([]byte{merkleLeafPrefix})
+	d.Write(leaf)
+	return d.Sum(nil)
+}
+
+func hashNode(h func() hash.Hash, left, right []byte) []byte {
+	d := h()
+	d.Write([]byte{merkleNodePrefix})
+	d.Write(left)
+	d.Write(right)
+	return d.Sum(nil)
+}
+
+// symbolLeaf encodes a symbol as a leaf in little endian.
+func symbolLeaf(v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, v)
+}
+
+// CommitCodeword builds a tree whose leaf i is symbol i of codeword.
+func CommitCodeword(codeword []uint64, h func() hash.Hash) (*MerkleTree, error) {
+	leaves := make([][]byte, len(codeword))
+	for i, v := range codeword {
+		leaves[i] = symbolLeaf(v)
+	}
+	return NewMerkleTree(leaves, h)
+}
+
+// CommitShards builds a tree whose leaf i is the content of shard i.
+func CommitShards(shards [][]uint64, h func() hash.Hash) (*MerkleTree, error) {
+	leaves := make([][]byte, len(shards))
+	for i, shard := range shards {
+		leaf := make([]byte, 0, 8*len(shard))
+		for _, v := range shard {
+			leaf = binary.LittleEndian.AppendUint64(leaf, v)
+		}
+		leaves[i] = leaf
+	}
+	return NewMerkleTree(leaves, h)
+}
+
+// Root returns the root of the tree.
+func (t *MerkleTree) Root() []byte {
+	return t.layers[len(t.layers)-1][0]
+}
+
+// Leaves returns number of leaves.
+func (t *MerkleTree) Leaves() int {
+	return t.leaves
+}
+
+// depth returns number of layers above leaves.
+func (t *MerkleTree) depth() int {
+	return len(t.layers) - 1
+}
+
+// MerkleProof is an inclusion proof of a single leaf.
+type MerkleProof struct {
+	Index int
+	// Siblings from leaf layer to the root
+	Siblings [][]byte
+}
+
+// Prove returns inclusion proof of leaf at index.
+func (t *MerkleTree) Prove(index int) (*MerkleProof, error) {
+	if index < 0 || index >= t.leaves {
+		return nil, fmt.Errorf("leaf index %d is out of range", index)
+	}
+	p := &MerkleProof{Index: index}
+	for d := 0; d < t.depth(); d++ {
+		p.Siblings = append(p.Siblings, t.layers[d][(index>>d)^1])
+	}
+	return p, nil
+}
+
+// VerifyMerkleProof checks that leaf is at index of the tree with the root.
+func VerifyMerkleProof(root, leaf []byte, p *MerkleProof, h func() hash.Hash) bool {
+	if h == nil {
+		h = sha256.New
+	}
+	if p.Index < 0 || p.Index>>len(p.Siblings) != 0 {
+		return false
+	}
+	node := hashLeaf(h, leaf)
+	for d, sibling := range p.Siblings {
+		if (p.Index>>d)&1 == 0 {
+			node = hashNode(h, node, sibling)
+		} else {
+			node = hashNode(h, sibling, node)
+		}
+	}
+	return bytes.Equal(node, root)
+}
+
+// MerkleMultiproof is an inclusion proof of several leaves. It carries only
+// the nodes that can not be computed from the proven leaves, layer by layer
+// from leaves to the root and in increasing index order in a layer.
+type MerkleMultiproof struct {
+	Indices []int
+	Depth   int
+	Nodes   [][]byte
+}
+
+// ProveMulti returns a proof of leaves at indices.
+func (t *MerkleTree) ProveMulti(indices []int) (*MerkleMultiproof, error) {
+	known, err := sortedIndices(indices, t.leaves)
+	if err != nil {
+		return nil, err
+	}
+	p := &MerkleMultiproof{Indices: known, Depth: t.depth()}
+	for d := 0; d < t.depth(); d++ {
+		set := make(map[int]bool, len(known))
+		for _, i := range known {
+			set[i] = true
+		}
+		parents := []int{}
+		for _, i := range known {
+			if i&1 == 1 && set[i^1] {
+				continue
+			}
+			if !set[i^1] {
+				p.Nodes = append(p.Nodes, t.layers[d][i^1])
+			}
+			parents = append(parents, i>>1)
+		}
+		known = parents
+	}
+	return p, nil
+}
+
+// VerifyMerkleMultiproof checks that leaves are at indices of the proof
+// in the tree with the root. leaves[j] is the leaf at j-th of sorted indices.
+func VerifyMerkleMultiproof(root []byte, leaves [][]byte, p *MerkleMultiproof, h func() hash.Hash) bool {
+	if h == nil {
+		h = sha256.New
+	}
+	if p.Depth < 0 || p.Depth > 62 || len(leaves) != len(p.Indices) {
+		return false
+	}
+	indices, err := sortedIndices(p.Indices, 1<<p.Depth)
+	if err != nil || len(indices) != len(p.Indices) {
+		return false
+	}
+	nodes := make(map[int][]byte, len(indices))
+	for j, i := range indices {
+		if i != p.Indices[j] {
+			return false
+		}
+		nodes[i] = hashLeaf(h, leaves[j])
+	}
+	next := 0
+	for d := 0; d < p.Depth; d++ {
+		parents := []int{}
+		layer := make(map[int][]byte, len(indices))
+		for _, i := range indices {
+			if i&1 == 1 {
+				if _, ok := nodes[i^1]; ok {
+					continue
+				}
+			}
+			sibling, ok := nodes[i^1]
+			if !ok {
+				if next == len(p.Nodes) {
+					return false
+				}
+				sibling = p.Nodes[next]
+				next++
+			}
+			if i&1 == 0 {
+				layer[i>>1] = hashNode(h, nodes[i], sibling)
+			} else {
+				layer[i>>1] = hashNode(h, sibling, nodes[i])
+			}
+			parents = append(parents, i>>1)
+		}
+		nodes, indices = layer, parents
+	}
+	return next == len(p.Nodes) && len(indices) == 1 && bytes.Equal(nodes[0], root)
+}
+
+// sortedIndices returns sorted distinct indices checking that they are in range.
+func sortedIndices(indices []int, n int) ([]int, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no index to prove")
+	}
+	sorted := append([]int{}, indices...)
+	sort.Ints(sorted)
+	out := sorted[:0]
+	for j, i := range sorted {
+		if i < 0 || i >= n {
+			return nil, fmt.Errorf("leaf index %d is out of range", i)
+		}
+		if j == 0 || i != sorted[j-1] {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
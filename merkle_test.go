@@ -0,0 +1,172 @@
+package gf
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"math/rand"
+	"testing"
+)
+
+func randLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = make([]byte, 1+rand.Intn(40))
+		rand.Read(leaves[i])
+	}
+	return leaves
+}
+
+func TestMerkleProof(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 16, 33} {
+		leaves := randLeaves(n)
+		tree, err := NewMerkleTree(leaves, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := tree.Root()
+		for i := 0; i < n; i++ {
+			p, err := tree.Prove(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Siblings) != log2Ceil(n) {
+				t.Fatal("bad proof length", n, len(p.Siblings))
+			}
+			if !VerifyMerkleProof(root, leaves[i], p, nil) {
+				t.Fatal("proof must be accepted", n, i)
+			}
+			if VerifyMerkleProof(root, append([]byte{1}, leaves[i]...), p, nil) {
+				t.Fatal("proof of a bad leaf must be rejected", n, i)
+			}
+			if n > 1 {
+				p.Index ^= 1
+				if VerifyMerkleProof(root, leaves[i], p, nil) {
+					t.Fatal("proof at another index must be rejected", n, i)
+				}
+				p.Index ^= 1
+				p.Siblings[0] = append([]byte{}, p.Siblings[0]...)
+				p.Siblings[0][0] ^= 1
+				if VerifyMerkleProof(root, leaves[i], p, nil) {
+					t.Fatal("proof with a bad sibling must be rejected", n, i)
+				}
+			}
+		}
+		if _, err := tree.Prove(n); err == nil {
+			t.Fatal("index out of range must be rejected")
+		}
+	}
+	if _, err := NewMerkleTree(nil, nil); err == nil {
+		t.Fatal("empty tree must be rejected")
+	}
+}
+
+func TestMerkleMultiproof(t *testing.T) {
+	n := 100
+	leaves := randLeaves(n)
+	tree, err := NewMerkleTree(leaves, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.Root()
+	for trial := 0; trial < 50; trial++ {
+		indices := make([]int, 1+rand.Intn(20))
+		for j := range indices {
+			indices[j] = rand.Intn(n)
+		}
+		p, err := tree.ProveMulti(indices)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sampled := make([][]byte, len(p.Indices))
+		for j, i := range p.Indices {
+			sampled[j] = leaves[i]
+		}
+		if !VerifyMerkleMultiproof(root, sampled, p, nil) {
+			t.Fatal("multiproof must be accepted", p.Indices)
+		}
+		if len(p.Nodes) > len(p.Indices)*tree.depth() {
+			t.Fatal("multiproof must not be larger than single proofs")
+		}
+		sampled[0] = append([]byte{1}, sampled[0]...)
+		if VerifyMerkleMultiproof(root, sampled, p, nil) {
+			t.Fatal("multiproof of a bad leaf must be rejected")
+		}
+		sampled[0] = leaves[p.Indices[0]]
+		if len(p.Nodes) != 0 {
+			short := *p
+			short.Nodes = p.Nodes[1:]
+			if VerifyMerkleMultiproof(root, sampled, &short, nil) {
+				t.Fatal("multiproof with a missing node must be rejected")
+			}
+		}
+	}
+
+	// all leaves are proven without any node
+	all := make([]int, n)
+	for i := range all {
+		all[i] = n - 1 - i
+	}
+	p, err := tree.ProveMulti(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyMerkleMultiproof(root, leaves, p, nil) {
+		t.Fatal("multiproof of all leaves must be accepted")
+	}
+	if _, err := tree.ProveMulti([]int{0, n}); err == nil {
+		t.Fatal("index out of range must be rejected")
+	}
+}
+
+func TestCommitCodeword(t *testing.T) {
+	c, err := NewCodec(16, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codeword, err := c.Encode(randPoly(10).a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := CommitCodeword(codeword, sha512.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Root()) != sha512.Size {
+		t.Fatal("root must be a digest of the given hash")
+	}
+	p, err := tree.ProveMulti([]int{3, 11, 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampled := [][]byte{symbolLeaf(codeword[3]), symbolLeaf(codeword[11]), symbolLeaf(codeword[12])}
+	if !VerifyMerkleMultiproof(tree.Root(), sampled, p, sha512.New) {
+		t.Fatal("sampled symbols must be accepted")
+	}
+	if VerifyMerkleMultiproof(tree.Root(), sampled, p, nil) {
+		t.Fatal("proof must be rejected with another hash")
+	}
+
+	// shard i of a stream is symbol i of each codeword
+	shards := codecShards(t, c, 4)
+	shardTree, err := CommitShards(shards, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		leaf := make([]byte, 0)
+		for _, v := range shards[i] {
+			leaf = append(leaf, symbolLeaf(v)...)
+		}
+		sp, err := shardTree.Prove(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyMerkleProof(shardTree.Root(), leaf, sp, nil) {
+			t.Fatal("shard proof must be accepted", i)
+		}
+	}
+	other, _ := CommitShards(shards[:15], nil)
+	if bytes.Equal(other.Root(), shardTree.Root()) {
+		t.Fatal("roots of different shard sets must differ")
+	}
+}
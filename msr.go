@@ -0,0 +1,326 @@
+package gf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MSR is a minimum storage regenerating code built with product matrix
+// construction of Rashmi, Shah and Kumar at d = 2k - 2 helpers. A message
+// of B = k(k - 1) sub-shards is stored in n shards of α = k - 1 sub-shards,
+// so that any k shards reconstruct the message as in an MDS code. A lost
+// shard is regenerated downloading a single sub-shard from each of d
+// helpers, that is d / α ≈ 2 sub-shards per stored sub-shard rather than
+// k sub-shards per stored sub-shard of full decoding.
+//
+// Message sub-shards fill upper triangles of two symmetric α x α matrices
+// S1 and S2, M = [S1; S2]. Shard i stores ψ_i M where ψ_i = [1, x_i, ..., x_i^(d-1)],
+// φ_i are first α entries of ψ_i and λ_i = x_i^α, so that ψ_i = [φ_i, λ_i φ_i].
+// Points x_i are powers of an element of high multiplicative order and
+// are checked so that x_i and λ_i are distinct.
+//
+// Trace repair of Reed-Solomon codes over GF(2) is not used as with 64 bit
+// symbols a helper has to send 64 - log(n - k) bits, which saves bandwidth
+// only for very long codes.
+type MSR struct {
+	n, k, d, alpha int
+	// psi[i] is the encoding vector of shard i of length d
+	psi    [][]uint64
+	lambda []uint64
+}
+
+// msrGenerator is an element outside proper subfields of GF(2^64).
+const msrGenerator = defaultBasisGenerator
+
+// NewMSR returns a minimum storage regenerating code with n shards of which
+// k reconstruct the message. n is expected to be at least 2k - 1.
+func NewMSR(n, k int) (*MSR, error) {
+	return newMSR(n, k, msrGenerator)
+}
+
+// newMSR builds the code with points x_i = γ^(i + 1). Powers of γ repeat
+// after its multiplicative order, so that points and λ_i are checked to be
+// distinct and nonzero rather than bounding n (k - 1) below the order.
+func newMSR(n, k int, gamma uint64) (*MSR, error) {
+	if k < 2 || n < 2*k-1 {
+		return nil, fmt.Errorf("bad code parameters n = %d, k = %d", n, k)
+	}
+	c := &MSR{n: n, k: k, d: 2*k - 2, alpha: k - 1}
+	c.psi = make([][]uint64, n)
+	c.lambda = make([]uint64, n)
+	points := map[uint64]bool{}
+	lambdas := map[uint64]bool{}
+	x := one()
+	for i := 0; i < n; i++ {
+		mulassign64(&x, gamma)
+		c.psi[i] = make([]uint64, c.d)
+		c.psi[i][0] = 1
+		for r := 1; r < c.d; r++ {
+			c.psi[i][r] = mul64(c.psi[i][r-1], x)
+		}
+		c.lambda[i] = c.psi[i][c.alpha]
+		if x == 0 || points[x] {
+			return nil, fmt.Errorf("point of shard %d is zero or repeated", i)
+		}
+		if c.lambda[i] == 0 || lambdas[c.lambda[i]] {
+			return nil, fmt.Errorf("λ of shard %d is zero or repeated", i)
+		}
+		points[x], lambdas[c.lambda[i]] = true, true
+	}
+	return c, nil
+}
+
+// N returns number of shards.
+func (c *MSR) N() int {
+	return c.n
+}
+
+// K returns number of shards that reconstruct the message.
+func (c *MSR) K() int {
+	return c.k
+}
+
+// D returns number of helpers of a repair.
+func (c *MSR) D() int {
+	return c.d
+}
+
+// Alpha returns number of sub-shards in a shard.
+func (c *MSR) Alpha() int {
+	return c.alpha
+}
+
+// MessageSize returns number of message sub-shards.
+func (c *MSR) MessageSize() int {
+	return c.k * c.alpha
+}
+
+// phi returns φ_i.
+func (c *MSR) phi(i int) []uint64 {
+	return c.psi[i][:c.alpha]
+}
+
+// Bandwidth returns number of symbols downloaded to repair a shard of
+// size symbols and to decode the message, which is also the cost of
+// repairing the shard with full decoding.
+func (c *MSR) Bandwidth(size int) (repair, decode int) {
+	return c.d * size / c.alpha, c.k * size
+}
+
+// symmetric returns index of entry (i, j) of a symmetric matrix in
+// message where entries of upper triangle are numbered in row order.
+func (c *MSR) symmetric(i, j int) int {
+	if i > j {
+		i, j = j, i
+	}
+	return i*c.alpha - i*(i-1)/2 + j - i
+}
+
+// Encode encodes message of B sub-shards of equal length, concatenated
+// in data, into n shards of α sub-shards each.
+func (c *MSR) Encode(data []uint64) ([][]uint64, error) {
+	B := c.MessageSize()
+	if len(data) == 0 || len(data)%B != 0 {
+		return nil, fmt.Errorf("message length %d is expected to be a positive multiple of %d", len(data), B)
+	}
+	L := len(data) / B
+	half := B / 2
+	shards := make([][]uint64, c.n)
+	for i := 0; i < c.n; i++ {
+		shards[i] = make([]uint64, c.alpha*L)
+		for t := 0; t < c.alpha; t++ {
+			out := shards[i][t*L : (t+1)*L]
+			for r := 0; r < c.alpha; r++ {
+				// M[r][t] = S1[r][t] and M[α + r][t] = S2[r][t]
+				u := c.symmetric(r, t)
+				w1, w2 := c.psi[i][r], c.psi[i][c.alpha+r]
+				s1, s2 := data[u*L:(u+1)*L], data[(half+u)*L:(half+u+1)*L]
+				for j := 0; j < L; j++ {
+					out[j] ^= mul64(w1, s1[j]) ^ mul64(w2, s2[j])
+				}
+			}
+		}
+	}
+	return shards, nil
+}
+
+// RepairSymbols computes contribution of a helper to the repair of failed
+// shard, which is a single sub-shard ψ_helper M φ_failed.
+func (c *MSR) RepairSymbols(shard []uint64, failed int) ([]uint64, error) {
+	if failed < 0 || failed >= c.n {
+		return nil, fmt.Errorf("shard index %d is out of range", failed)
+	}
+	if len(shard) == 0 || len(shard)%c.alpha != 0 {
+		return nil, fmt.Errorf("shard length %d is expected to be a positive multiple of %d", len(shard), c.alpha)
+	}
+	L := len(shard) / c.alpha
+	out := make([]uint64, L)
+	for t, w := range c.phi(failed) {
+		for j := 0; j < L; j++ {
+			out[j] ^= mul64(w, shard[t*L+j])
+		}
+	}
+	return out, nil
+}
+
+// Repair regenerates failed shard from contributions of d distinct helpers.
+// Contributions Ψ_H M φ_f are inverted to M φ_f = [S1 φ_f; S2 φ_f] and with
+// symmetry the shard is (S1 φ_f)^T + λ_f (S2 φ_f)^T.
+func (c *MSR) Repair(failed int, helpers []int, contributions [][]uint64) ([]uint64, error) {
+	if failed < 0 || failed >= c.n {
+		return nil, fmt.Errorf("shard index %d is out of range", failed)
+	}
+	if len(helpers) != c.d || len(contributions) != c.d {
+		return nil, fmt.Errorf("expected %d helpers", c.d)
+	}
+	rows := make([][]uint64, c.d)
+	seen := map[int]bool{}
+	for h, i := range helpers {
+		if i < 0 || i >= c.n || i == failed || seen[i] {
+			return nil, fmt.Errorf("bad helper %d", i)
+		}
+		seen[i] = true
+		rows[h] = c.psi[i]
+		if len(contributions[h]) != len(contributions[0]) {
+			return nil, errors.New("contributions are expected to be of equal length")
+		}
+	}
+	inv, err := invertMatrix(rows)
+	if err != nil {
+		return nil, err
+	}
+	L := len(contributions[0])
+	shard := make([]uint64, c.alpha*L)
+	for t := 0; t < c.alpha; t++ {
+		out := shard[t*L : (t+1)*L]
+		for h := 0; h < c.d; h++ {
+			w := inv[t][h] ^ mul64(c.lambda[failed], inv[c.alpha+t][h])
+			for j := 0; j < L; j++ {
+				out[j] ^= mul64(w, contributions[h][j])
+			}
+		}
+	}
+	return shard, nil
+}
+
+// Decode reconstructs the message from any k shards, missing shards are nil.
+func (c *MSR) Decode(shards [][]uint64) ([]uint64, error) {
+	if len(shards) != c.n {
+		return nil, fmt.Errorf("expected %d shards, got %d", c.n, len(shards))
+	}
+	dc := []int{}
+	for i := 0; i < c.n && len(dc) < c.k; i++ {
+		if shards[i] != nil {
+			if len(dc) != 0 && len(shards[i]) != len(shards[dc[0]]) {
+				return nil, errors.New("shards are expected to be of equal length")
+			}
+			dc = append(dc, i)
+		}
+	}
+	if len(dc) < c.k {
+		return nil, fmt.Errorf("not enough shards to decode, %d of %d", len(dc), c.k)
+	}
+	if len(shards[dc[0]])%c.alpha != 0 {
+		return nil, fmt.Errorf("shard length is expected to be a multiple of %d", c.alpha)
+	}
+	L := len(shards[dc[0]]) / c.alpha
+
+	// for first α nodes a of data collector, inverses of [φ_b] for b in
+	// data collector other than a and of [φ_a]
+	others := make([][][]uint64, c.alpha)
+	for a := 0; a < c.alpha; a++ {
+		rows := [][]uint64{}
+		for b := 0; b < c.k; b++ {
+			if b != a {
+				rows = append(rows, c.phi(dc[b]))
+			}
+		}
+		inv, err := invertMatrix(rows)
+		if err != nil {
+			return nil, err
+		}
+		others[a] = inv
+	}
+	rows := make([][]uint64, c.alpha)
+	for a := 0; a < c.alpha; a++ {
+		rows[a] = c.phi(dc[a])
+	}
+	first, err := invertMatrix(rows)
+	if err != nil {
+		return nil, err
+	}
+	invDiff := make([][]uint64, c.k)
+	for a := 0; a < c.k; a++ {
+		invDiff[a] = make([]uint64, c.k)
+		for b := 0; b < c.k; b++ {
+			if a != b {
+				invDiff[a][b] = inverse(c.lambda[dc[a]] ^ c.lambda[dc[b]])
+			}
+		}
+	}
+
+	B := c.MessageSize()
+	data := make([]uint64, B*L)
+	Y := newMatrix(c.k, c.k)
+	P, Q := newMatrix(c.k, c.k), newMatrix(c.k, c.k)
+	rowP, rowQ := newMatrix(c.alpha, c.alpha), newMatrix(c.alpha, c.alpha)
+	for j := 0; j < L; j++ {
+		// Y = Ψ_DC M Φ_DC^T with Y_ab = P_ab + λ_a Q_ab
+		for a := 0; a < c.k; a++ {
+			for b := 0; b < c.k; b++ {
+				var acc uint64
+				for t, w := range c.phi(dc[b]) {
+					acc ^= mul64(w, shards[dc[a]][t*L+j])
+				}
+				Y[a][b] = acc
+			}
+		}
+		for a := 0; a < c.k; a++ {
+			for b := a + 1; b < c.k; b++ {
+				q := mul64(Y[a][b]^Y[b][a], invDiff[a][b])
+				Q[a][b], Q[b][a] = q, q
+				p := Y[a][b] ^ mul64(c.lambda[dc[a]], q)
+				P[a][b], P[b][a] = p, p
+			}
+		}
+		// φ_a^T S1 from P_ab = φ_a^T S1 φ_b for b other than a
+		for a := 0; a < c.alpha; a++ {
+			for t := 0; t < c.alpha; t++ {
+				var accP, accQ uint64
+				e := 0
+				for b := 0; b < c.k; b++ {
+					if b == a {
+						continue
+					}
+					w := others[a][t][e]
+					accP ^= mul64(P[a][b], w)
+					accQ ^= mul64(Q[a][b], w)
+					e++
+				}
+				rowP[a][t], rowQ[a][t] = accP, accQ
+			}
+		}
+		// S = [φ_a]^-1 [φ_a^T S]
+		for r := 0; r < c.alpha; r++ {
+			for t := r; t < c.alpha; t++ {
+				var s1, s2 uint64
+				for a := 0; a < c.alpha; a++ {
+					s1 ^= mul64(first[r][a], rowP[a][t])
+					s2 ^= mul64(first[r][a], rowQ[a][t])
+				}
+				u := c.symmetric(r, t)
+				data[u*L+j] = s1
+				data[(B/2+u)*L+j] = s2
+			}
+		}
+	}
+	return data, nil
+}
+
+func newMatrix(rows, cols int) [][]uint64 {
+	m := make([][]uint64, rows)
+	for i := range m {
+		m[i] = make([]uint64, cols)
+	}
+	return m
+}
@@ -0,0 +1,101 @@
+package gf
+
+import (
+	"testing"
+)
+
+func TestMSR(t *testing.T) {
+	for _, p := range [][2]int{{3, 2}, {8, 3}, {12, 5}} {
+		c, err := NewMSR(p[0], p[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		L := 3
+		data := randPoly(c.MessageSize() * L).a
+		shards, err := c.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// decode from every window of k shards
+		for s := 0; s+c.k <= c.n; s++ {
+			partial := make([][]uint64, c.n)
+			copy(partial[s:s+c.k], shards[s:s+c.k])
+			decoded, err := c.Decode(partial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !newPoly(decoded).equalInCoeff(newPoly(data)) {
+				t.Fatal("bad decoding", p, s)
+			}
+		}
+
+		// repair every shard from the last d other shards
+		for f := 0; f < c.n; f++ {
+			helpers := []int{}
+			contributions := [][]uint64{}
+			for i := c.n - 1; i >= 0 && len(helpers) < c.d; i-- {
+				if i == f {
+					continue
+				}
+				h, err := c.RepairSymbols(shards[i], f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				helpers = append(helpers, i)
+				contributions = append(contributions, h)
+			}
+			repaired, err := c.Repair(f, helpers, contributions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !newPoly(repaired).equalInCoeff(newPoly(shards[f])) {
+				t.Fatal("bad repair", p, f)
+			}
+		}
+	}
+}
+
+func TestMSRBandwidth(t *testing.T) {
+	c, err := NewMSR(16, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := c.Alpha() * 100
+	repair, decode := c.Bandwidth(size)
+	if repair != c.D()*100 || decode != c.K()*size {
+		t.Fatal("bad bandwidth")
+	}
+	if 2*repair >= decode {
+		t.Fatal("repair must download much less than decoding", repair, decode)
+	}
+}
+
+func TestMSRRejectsBadInput(t *testing.T) {
+	if _, err := NewMSR(4, 3); err == nil {
+		t.Fatal("n < 2k - 1 must be rejected")
+	}
+	// w of order 15 gives distinct points w^1, ..., w^7 but λ_5 = w^18 = λ_0
+	w := exp(msrGenerator, (1<<64-1)/15)
+	if _, err := newMSR(7, 4, w); err == nil {
+		t.Fatal("repeated λ must be rejected")
+	}
+	if _, err := newMSR(4, 2, exp(w, 5)); err == nil {
+		t.Fatal("repeated point must be rejected")
+	}
+	c, err := NewMSR(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, err := c.Encode(randPoly(c.MessageSize()).a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards[0], shards[1], shards[2] = nil, nil, nil
+	if _, err := c.Decode(shards); err == nil {
+		t.Fatal("decoding must fail with less than k shards")
+	}
+	if _, err := c.Repair(0, []int{1, 1, 2, 3}, make([][]uint64, 4)); err == nil {
+		t.Fatal("repeated helpers must be rejected")
+	}
+}
@@ -0,0 +1,135 @@
+package gf
+
+import (
+	"fmt"
+)
+
+// multilinear is a multilinear polynomial in v variables over GF(2^64) given
+// by its evaluations over the boolean hypercube, evaluation at x being at
+// index sum x_j 2^j. Variables are bound from the last one, so that folding
+// combines the lower and upper halves of evaluations.
+type multilinear struct {
+	evals []uint64
+}
+
+func newMultilinear(evals []uint64) (*multilinear, error) {
+	n := len(evals)
+	if n == 0 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("number of evaluations is expected to be a power of two: %d", n)
+	}
+	return &multilinear{evals: evals}, nil
+}
+
+func randMultilinear(v int) *multilinear {
+	evals := make([]uint64, 1<<v)
+	for i := range evals {
+		evals[i] = randGF64()
+	}
+	return &multilinear{evals: evals}
+}
+
+// vars returns number of variables.
+func (m *multilinear) vars() int {
+	return log2Floor(len(m.evals))
+}
+
+// sum returns sum of evaluations over the hypercube.
+func (m *multilinear) sum() uint64 {
+	var s uint64
+	for _, e := range m.evals {
+		s ^= e
+	}
+	return s
+}
+
+// fold binds the last variable to r, f(.., r) = f(.., 0) + r (f(.., 0) + f(.., 1)).
+func (m *multilinear) fold(r uint64) *multilinear {
+	h := len(m.evals) / 2
+	lo, hi := m.evals[:h], m.evals[h:]
+	out := make([]uint64, h)
+	for i := range out {
+		out[i] = lo[i] ^ mul64(r, lo[i]^hi[i])
+	}
+	return &multilinear{evals: out}
+}
+
+// partialEvaluate binds last len(point) variables where point[t] is bound
+// to variable v - len(point) + t.
+func (m *multilinear) partialEvaluate(point []uint64) (*multilinear, error) {
+	if len(point) > m.vars() {
+		return nil, fmt.Errorf("point of %d variables for a polynomial of %d variables", len(point), m.vars())
+	}
+	f := m
+	for t := len(point) - 1; t >= 0; t-- {
+		f = f.fold(point[t])
+	}
+	if f == m {
+		f = &multilinear{evals: append([]uint64{}, m.evals...)}
+	}
+	return f, nil
+}
+
+// evaluate evaluates at a point of v coordinates in 2^v multiplications,
+// folding in place a single copy of evaluations.
+func (m *multilinear) evaluate(point []uint64) (uint64, error) {
+	if len(point) != m.vars() {
+		return 0, fmt.Errorf("point of %d variables for a polynomial of %d variables", len(point), m.vars())
+	}
+	buf := append([]uint64{}, m.evals...)
+	for t := len(point) - 1; t >= 0; t-- {
+		h := len(buf) / 2
+		r := point[t]
+		for i := 0; i < h; i++ {
+			buf[i] ^= mul64(r, buf[i]^buf[h+i])
+		}
+		buf = buf[:h]
+	}
+	return buf[0], nil
+}
+
+// foldExt binds the last variable to r in the tower extension GF(2^128).
+// Multiplication of an extension element by a base element takes two
+// multiplications in GF(2^64).
+func (m *multilinear) foldExt(r tower128) *multilinearExt {
+	h := len(m.evals) / 2
+	lo, hi := m.evals[:h], m.evals[h:]
+	out := make([]tower128, h)
+	for i := range out {
+		d := lo[i] ^ hi[i]
+		out[i] = tower128{lo[i] ^ mul64(r[0], d), mul64(r[1], d)}
+	}
+	return &multilinearExt{evals: out}
+}
+
+// evaluateExt evaluates at a point in the tower extension GF(2^128).
+func (m *multilinear) evaluateExt(point []tower128) (tower128, error) {
+	if len(point) != m.vars() {
+		return tower128{}, fmt.Errorf("point of %d variables for a polynomial of %d variables", len(point), m.vars())
+	}
+	if len(point) == 0 {
+		return tower128{m.evals[0], 0}, nil
+	}
+	f := m.foldExt(point[len(point)-1])
+	for t := len(point) - 2; t >= 0; t-- {
+		f = f.fold(point[t])
+	}
+	return f.evals[0], nil
+}
+
+// multilinearExt is a multilinear polynomial over GF(2^128) in tower
+// representation, which folded polynomials of sumcheck are.
+type multilinearExt struct {
+	evals []tower128
+}
+
+// fold binds the last variable to r.
+func (m *multilinearExt) fold(r tower128) *multilinearExt {
+	h := len(m.evals) / 2
+	lo, hi := m.evals[:h], m.evals[h:]
+	out := make([]tower128, h)
+	for i := range out {
+		p := mulTower(r, tower128{lo[i][0] ^ hi[i][0], lo[i][1] ^ hi[i][1]})
+		out[i] = tower128{lo[i][0] ^ p[0], lo[i][1] ^ p[1]}
+	}
+	return &multilinearExt{evals: out}
+}
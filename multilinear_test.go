@@ -0,0 +1,130 @@
+package gf
+
+import (
+	"testing"
+)
+
+// eqNaive evaluates at point as sum of f(x) eq(x, point) over the hypercube.
+func eqNaive(m *multilinear, point []uint64) uint64 {
+	var acc uint64
+	for x, e := range m.evals {
+		w := e
+		for j, r := range point {
+			if x>>j&1 == 1 {
+				mulassign64(&w, r)
+			} else {
+				mulassign64(&w, r^1)
+			}
+		}
+		acc ^= w
+	}
+	return acc
+}
+
+func randPoint(v int) []uint64 {
+	point := make([]uint64, v)
+	for j := range point {
+		point[j] = randGF64()
+	}
+	return point
+}
+
+func TestMultilinearEvaluate(t *testing.T) {
+	for _, v := range []int{0, 1, 4, 9} {
+		m := randMultilinear(v)
+		for x := 0; x < 1<<v; x += 1 + x {
+			point := make([]uint64, v)
+			for j := range point {
+				point[j] = uint64(x >> j & 1)
+			}
+			e, err := m.evaluate(point)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e != m.evals[x] {
+				t.Fatal("evaluation at hypercube must be the given evaluation", v, x)
+			}
+		}
+		point := randPoint(v)
+		e, err := m.evaluate(point)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e != eqNaive(m, point) {
+			t.Fatal("evaluation must be the multilinear extension", v)
+		}
+		for bound := 0; bound <= v; bound++ {
+			f, err := m.partialEvaluate(point[v-bound:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.vars() != v-bound {
+				t.Fatal("partial evaluation must bind variables", v, bound)
+			}
+			pe, err := f.evaluate(point[:v-bound])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pe != e {
+				t.Fatal("partial evaluation must be consistent", v, bound)
+			}
+		}
+		ext := make([]tower128, v)
+		for j := range ext {
+			ext[j] = tower128{point[j], 0}
+		}
+		ee, err := m.evaluateExt(ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ee != (tower128{e, 0}) {
+			t.Fatal("extension evaluation at base point must be in the base field", v)
+		}
+	}
+	if _, err := newMultilinear(make([]uint64, 3)); err == nil {
+		t.Fatal("evaluations must be a power of two")
+	}
+	if _, err := randMultilinear(3).evaluate(randPoint(2)); err == nil {
+		t.Fatal("point of wrong size must be rejected")
+	}
+}
+
+func TestMultilinearEvaluateExt(t *testing.T) {
+	v := 6
+	m := randMultilinear(v)
+	point := make([]tower128, v)
+	for j := range point {
+		point[j] = randTower128()
+	}
+	e, err := m.evaluateExt(point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// naive sum of f(x) eq(x, point) in the extension
+	var acc tower128
+	for x, fx := range m.evals {
+		w := tower128{fx, 0}
+		for j, r := range point {
+			if x>>j&1 == 1 {
+				w = mulTower(w, r)
+			} else {
+				w = mulTower(w, tower128{r[0] ^ 1, r[1]})
+			}
+		}
+		acc[0] ^= w[0]
+		acc[1] ^= w[1]
+	}
+	if acc != e {
+		t.Fatal("extension evaluation must be the multilinear extension")
+	}
+}
+
+func BenchmarkMultilinearEvaluate(t *testing.B) {
+	v := 16
+	m := randMultilinear(v)
+	point := randPoint(v)
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		_, _ = m.evaluate(point)
+	}
+}
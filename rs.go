@@ -3,37 +3,241 @@ package gf
 import (
 	"errors"
 	"fmt"
+	"math/bits"
 )
 
+// polyBasis is the basis that coefficients of message polynomial are given in.
+type polyBasis int
+
+const (
+	// monomialBasis is the basis of 1, x, x^2, ...
+	monomialBasis polyBasis = iota
+	// novelBasis is the novel polynomial basis of Lin, Chung and Han.
+	// Transforms in this basis skip radix conversion so that
+	// encoding and decoding are O(n log n).
+	novelBasis
+)
+
+func (b polyBasis) fft(p *poly) (*poly, error) {
+	if b == novelBasis {
+		return p.lfft()
+	}
+	return p.fft()
+}
+
+func (b polyBasis) ifft(p *poly) (*poly, error) {
+	if b == novelBasis {
+		return p.lifft()
+	}
+	return p.ifft()
+}
+
+func (b polyBasis) derivative(p *poly) *poly {
+	if b == novelBasis {
+		return p.novelDerivative()
+	}
+	return p.derivative()
+}
+
 func encode(data *poly, factor int) (*poly, error) {
+	return encodeIn(data, factor, monomialBasis)
+}
+
+// encodeNovel encodes message whose coefficients are in novel polynomial basis.
+func encodeNovel(data *poly, factor int) (*poly, error) {
+	return encodeIn(data, factor, novelBasis)
+}
+
+func encodeIn(data *poly, factor int, b polyBasis) (*poly, error) {
 	encodedData := data.clone()
 	encodedData.expand(data.length() * factor)
-	if _, err := encodedData.fft(); err != nil {
+	if _, err := b.fft(encodedData); err != nil {
 		return nil, err
 	}
 	return encodedData, nil
 }
 
+// recover reconstructs message polynomial D(x) from its evaluations
+// where values at missing indices are erased and set to zero.
+// With Z(x) vanishing at erased points, E(x)Z(x) = D(x)Z(x) on the whole domain
+// and since degree of DZ is less than domain size it is interpolated exactly.
+// Differentiating DZ gives (DZ)' = D'Z + DZ' so that at an erased point x_i
+// D(x_i) = (DZ)'(x_i) / Z'(x_i) where Z'(x_i) is nonzero as roots are simple.
+// Recovery is deterministic and succeeds whenever deg(D) + |missing| < n.
 func recover(erasureData *poly, missing []uint64) (*poly, error) {
+	return recoverIn(erasureData, missing, monomialBasis)
+}
+
+// recoverNovel reconstructs message polynomial in novel polynomial basis.
+// Apart from building the erasure locator, no radix conversion is applied.
+func recoverNovel(erasureData *poly, missing []uint64) (*poly, error) {
+	return recoverIn(erasureData, missing, novelBasis)
+}
+
+func recoverIn(erasureData *poly, missing []uint64, b polyBasis) (*poly, error) {
+	for _, i := range missing {
+		if i < uint64(erasureData.length()) && erasureData.a[i] != 0 {
+			return nil, fmt.Errorf("erasure data at %d expected to be zero", i)
+		}
+	}
+	d, err := newErasureDecoder(erasureData.length(), missing, b)
+	if err != nil {
+		return nil, err
+	}
+	return d.decode(erasureData)
+}
+
+// verify checks that codeword is evaluation of a polynomial of degree less
+// than k, that is interpolated polynomial has no coefficients at k and above.
+// It costs a single inverse transform and no division.
+func verify(codeword *poly, k int) (bool, error) {
+	return verifyIn(codeword, k, monomialBasis)
+}
+
+// verifyNovel checks codeword of a message in novel polynomial basis.
+func verifyNovel(codeword *poly, k int) (bool, error) {
+	return verifyIn(codeword, k, novelBasis)
+}
+
+func verifyIn(codeword *poly, k int, b polyBasis) (bool, error) {
+	if k < 0 || k > codeword.length() {
+		return false, fmt.Errorf("message length %d is out of range", k)
+	}
+	p, err := b.ifft(codeword.clone())
+	if err != nil {
+		return false, err
+	}
+	for i := k; i < p.length(); i++ {
+		if p.a[i] != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// column returns c times evaluations of basis polynomial at index over the
+// first n points of the domain. In both bases a basis polynomial is product
+// of GF(2)-linear maps over bits of the index, x^t = prod x^(2^j) in monomial
+// and X_t = prod ŝ_j(x) in novel basis. Values of a linear map at the points
+// follow from its values at bases with one addition per point, so that
+// a column costs O(n log t) rather than a full transform.
+func (b polyBasis) column(n, index int, c uint64) []uint64 {
+	m := log2Floor(n)
+	col := make([]uint64, n)
+	for i := 0; i < n; i++ {
+		col[i] = c
+	}
+	v := make([]uint64, n)
+	e := make([]uint64, m)
+	for j := 0; 1<<j <= index; j++ {
+		if index&(1<<j) == 0 {
+			continue
+		}
+		// linear map at bases
+		var s *subspacePoly
+		var norm uint64
+		if b == novelBasis {
+			s = newSubspacePoly(j)
+			norm = inverse(s.eval(defaultBasis().combinations[1<<j]))
+		}
+		for t := 0; t < m; t++ {
+			x := defaultBasis().combinations[1<<t]
+			if b == novelBasis {
+				e[t] = mul64(s.eval(x), norm)
+			} else {
+				for r := 0; r < j; r++ {
+					squareassign64(&x)
+				}
+				e[t] = x
+			}
+		}
+		// linear map at points, dropping lowest set bit of i
+		for i := 1; i < n; i++ {
+			v[i] = v[i&(i-1)] ^ e[bits.TrailingZeros(uint(i))]
+			mulassign64(&col[i], v[i])
+		}
+		col[0] = 0
+	}
+	return col
+}
 
-	m := erasureData.m()
-	n := 1 << m
+// update adds contribution of changing coefficient at index of message
+// polynomial from oldValue to newValue to its codeword in place. As the code
+// is linear, codeword changes by (newValue - oldValue) times evaluations of
+// the basis polynomial at index.
+func update(codeword *poly, index int, oldValue, newValue uint64) error {
+	return updateIn(codeword, index, oldValue, newValue, monomialBasis)
+}
+
+// updateNovel updates codeword of a message in novel polynomial basis.
+func updateNovel(codeword *poly, index int, oldValue, newValue uint64) error {
+	return updateIn(codeword, index, oldValue, newValue, novelBasis)
+}
+
+func updateIn(codeword *poly, index int, oldValue, newValue uint64, b polyBasis) error {
+	n := codeword.length()
+	if n != 1<<log2Floor(n) {
+		return fmt.Errorf("codeword length is expected to be power of two: %d", n)
+	}
+	if !fftFits(n) {
+		return fmt.Errorf("default basis is not large enough for domain size %d", n)
+	}
+	if index < 0 || index >= n {
+		return fmt.Errorf("message index %d is out of range", index)
+	}
+	delta := oldValue ^ newValue
+	if delta == 0 {
+		return nil
+	}
+	column := b.column(n, index, delta)
+	for i := 0; i < n; i++ {
+		codeword.a[i] ^= column[i]
+	}
+	return nil
+}
+
+// erasureDecoder keeps evaluations of the erasure locator Z(x) and inverses
+// of Z'(x) at erased points, so that codewords sharing the same erasures
+// are decoded with three transforms each.
+type erasureDecoder struct {
+	n       int
+	missing []uint64
+	b       polyBasis
+	// Z(x) evaluated over the domain
+	zVal *poly
+	// 1 / Z'(x_i) for each erased point
+	dzInv []uint64
+}
 
+func newErasureDecoder(n int, missing []uint64, b polyBasis) (*erasureDecoder, error) {
+
+	m := log2Ceil(n)
+	if n != 1<<m || n < 2 {
+		return nil, fmt.Errorf("erasure data length is expected to be power of two: %d", n)
+	}
+	if len(missing) >= n {
+		return nil, fmt.Errorf("too many missing data, %d of %d", len(missing), n)
+	}
+	if !fftFits(n) {
+		return nil, fmt.Errorf("default basis is not large enough for domain size %d", n)
+	}
+
+	seen := make(map[uint64]bool, len(missing))
 	I := make([]uint64, len(missing))
 	for k := 0; k < len(missing); k++ {
 		i := missing[k]
-		if i >= uint64(erasureData.length()) {
-			return nil, fmt.Errorf("missing data index %d exceeds erasure data length %d", i, erasureData.length())
+		if i >= uint64(n) {
+			return nil, fmt.Errorf("missing data index %d exceeds erasure data length %d", i, n)
 		}
-		if erasureData.a[i] != 0 {
-			return nil, fmt.Errorf("erasure data at %d expected to be zero", missing[k])
-
+		if seen[i] {
+			return nil, fmt.Errorf("missing data index %d is repeated", i)
 		}
-		I[k] = defaultBasis.combinations[i]
+		seen[i] = true
+		I[k] = defaultBasis().combinations[i]
 	}
 
 	// Z(x)
-	Zx, err := z(I)
+	Zx, err := erasureLocator(missing, I)
 	if err != nil {
 		return nil, err
 	}
@@ -45,37 +249,105 @@ func recover(erasureData *poly, missing []uint64) (*poly, error) {
 		return nil, err
 	}
 
+	// evaluations of formal derivative Z'(x)
+	dZx := Zx.derivative()
+	dZx.expand(n)
+	if _, err := dZx.fft(); err != nil {
+		return nil, err
+	}
+	dZxInv := newEmptyPoly(len(missing))
+	for k, i := range missing {
+		dZxInv.a[k] = dZx.a[i]
+	}
+	if _, err := dZxInv.invSample(); err != nil {
+		return nil, err
+	}
+
+	return &erasureDecoder{
+		n:       n,
+		missing: missing,
+		b:       b,
+		zVal:    Zxval,
+		dzInv:   dZxInv.a,
+	}, nil
+}
+
+// complete fills erased values of a codeword in place.
+// Values at erased points are ignored.
+func (d *erasureDecoder) complete(erasureData *poly) error {
+	if erasureData.length() != d.n {
+		return fmt.Errorf("erasure data length %d is expected to be %d", erasureData.length(), d.n)
+	}
+	if len(d.missing) == 0 {
+		return nil
+	}
+	for _, i := range d.missing {
+		erasureData.a[i] = 0
+	}
+
 	// DZ'(x) = Z'(x) * E'(x)
-	DZxval, err := Zxval.mulSample(erasureData)
+	DZxval, err := erasureData.clone().mulSample(d.zVal)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// DZ(x) = interpolate(DZ'(x))
-	DZx, err := DZxval.ifft()
+	DZx, err := d.b.ifft(DZxval)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// evaluations of formal derivative (DZ)'(x)
+	dDZx := d.b.derivative(DZx)
+	dDZx.expand(d.n)
+	if _, err := d.b.fft(dDZx); err != nil {
+		return err
 	}
 
-	// pick random k
-	// TODO: check if k is picked wisely
-	k := randGF64()
-	// Z(k * x)
-	Zkx := Zx.substitute(k)
-	// DZ(k * x)
-	DZxk := DZx.substitute(k)
+	// D(x_i) = (DZ)'(x_i) / Z'(x_i) at erased points
+	for k, i := range d.missing {
+		erasureData.a[i] = mul64(dDZx.a[i], d.dzInv[k])
+	}
+	return nil
+}
+
+// decode returns message polynomial of a codeword with erasures.
+func (d *erasureDecoder) decode(erasureData *poly) (*poly, error) {
+	Dxval := erasureData.clone()
+	if err := d.complete(Dxval); err != nil {
+		return nil, err
+	}
 
-	// D(k * x) = DZ(k * x) / Z(k * x)
-	Dxk, err := DZxk.div(Zkx)
+	// D(x)
+	Dx, err := d.b.ifft(Dxval)
 	if err != nil {
 		return nil, err
 	}
-	Dxk.trimZeros()
-	if Dxk.length() > 1<<(m-1) {
-		return nil, errors.New("perfect division is expected")
+	Dx.trimZeros()
+	if Dx.length()+len(d.missing) > d.n {
+		return nil, errors.New("not enough data to recover the message polynomial")
 	}
-	// D(x)
-	Dx := Dxk.substitute(inverse(k))
 	return Dx, nil
+}
 
+// erasureLocator returns vanishing polynomial of the points at missing indices.
+// If missing indices are union of aligned blocks of size 2^k, points are
+// cosets of span of first k bases and the polynomial is built from
+// the subspace polynomial s_k rather than from each of the points.
+func erasureLocator(missing []uint64, points []uint64) (*poly, error) {
+	k := alignedBlockDimension(missing)
+	if k == 0 {
+		return z(points)
+	}
+	s := newSubspacePoly(k)
+	seen := make(map[uint64]bool)
+	reps := []uint64{}
+	for _, i := range missing {
+		b := i >> k
+		if !seen[b] {
+			seen[b] = true
+			reps = append(reps, defaultBasis().combinations[b<<k])
+		}
+	}
+	return s.cosets(reps)
 }
@@ -0,0 +1,309 @@
+package gf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var defaultBasisMu sync.Mutex
+
+// ensureDefaultBasis makes sure that default basis spans at least 2^m points.
+// Spans of smaller bases are prefixes of larger ones so that
+// growing the basis keeps existing evaluation domains untouched.
+// Growth is serialized here while readers load the published basis.
+func ensureDefaultBasis(m int) {
+	defaultBasisMu.Lock()
+	defer defaultBasisMu.Unlock()
+	if b := defaultBasis(); b == nil || b.m < m {
+		initDefaultBasis(m)
+	}
+}
+
+// Codec is a Reed-Solomon code of length n and dimension k over GF(2^64).
+// A message of k symbols is taken as coefficients of a polynomial in novel
+// polynomial basis and its codeword is the evaluation of the polynomial
+// at the first n combinations of default basis. Symbol i of a codeword
+// is stored in shard i, so that any k shards are enough to reconstruct.
+type Codec struct {
+	n int
+	k int
+	m int
+}
+
+// MaxShards is the maximum number of shards of a codec. Default basis
+// spanning the evaluation domain is kept in memory, 2^20 points of it
+// taking 8 MiB.
+const MaxShards = 1 << 20
+
+// NewCodec returns a codec with n shards of which k are enough to
+// reconstruct. n is expected to be a power of two up to MaxShards
+// and 0 < k <= n.
+func NewCodec(n, k int) (*Codec, error) {
+	if n < 2 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("number of shards is expected to be a power of two: %d", n)
+	}
+	if n > MaxShards {
+		return nil, fmt.Errorf("number of shards %d exceeds %d", n, MaxShards)
+	}
+	if k < 1 || k > n {
+		return nil, fmt.Errorf("number of data shards is expected to be in [1, %d]: %d", n, k)
+	}
+	m := log2Floor(n)
+	ensureDefaultBasis(m)
+	return &Codec{n: n, k: k, m: m}, nil
+}
+
+// N returns number of shards.
+func (c *Codec) N() int {
+	return c.n
+}
+
+// K returns number of shards that are enough to reconstruct.
+func (c *Codec) K() int {
+	return c.k
+}
+
+// Encode encodes a message of at most k symbols into a codeword of n symbols.
+func (c *Codec) Encode(data []uint64) ([]uint64, error) {
+	if len(data) > c.k {
+		return nil, fmt.Errorf("message length %d exceeds %d", len(data), c.k)
+	}
+	ensureDefaultBasis(c.m)
+	p := newEmptyPoly(c.n)
+	copy(p.a, data)
+	if _, err := p.lfft(); err != nil {
+		return nil, err
+	}
+	return p.a, nil
+}
+
+// Decode reconstructs the message of k symbols from a codeword
+// where symbols at missing indices are unknown. Values of missing
+// symbols are ignored and codeword is not modified.
+func (c *Codec) Decode(codeword []uint64, missing []int) ([]uint64, error) {
+	p, err := c.NewDecodePlan(missing)
+	if err != nil {
+		return nil, err
+	}
+	return p.Decode(codeword)
+}
+
+// EncodeTower encodes a message of elements of binary tower level k, for
+// example bytes at level three, lifting them to GF(2^64).
+func (c *Codec) EncodeTower(data []uint64, k int) ([]uint64, error) {
+	lifted, err := btLift(k, data)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encode(lifted)
+}
+
+// DecodeTower decodes a message encoded with EncodeTower. Message symbols
+// that fall out of the subfield of level k reveal a corrupted codeword.
+func (c *Codec) DecodeTower(codeword []uint64, missing []int, k int) ([]uint64, error) {
+	data, err := c.Decode(codeword, missing)
+	if err != nil {
+		return nil, err
+	}
+	return btLower(k, data)
+}
+
+// UpdateParity updates a codeword in place when message symbol at index
+// changes from oldValue to newValue, without encoding the message again.
+// Message is not stored in the codeword, so that every shard depends on
+// every message symbol and whole codeword is updated.
+func (c *Codec) UpdateParity(codeword []uint64, index int, oldValue, newValue uint64) error {
+	if len(codeword) != c.n {
+		return fmt.Errorf("codeword length %d is expected to be %d", len(codeword), c.n)
+	}
+	if index < 0 || index >= c.k {
+		return fmt.Errorf("message index %d is out of range", index)
+	}
+	ensureDefaultBasis(c.m)
+	return updateNovel(newPoly(codeword), index, oldValue, newValue)
+}
+
+// Verify checks that a codeword is consistent, that is a codeword of some
+// message, with a single inverse transform.
+func (c *Codec) Verify(codeword []uint64) (bool, error) {
+	if len(codeword) != c.n {
+		return false, fmt.Errorf("codeword length %d is expected to be %d", len(codeword), c.n)
+	}
+	ensureDefaultBasis(c.m)
+	return verifyNovel(newPoly(codeword), c.k)
+}
+
+// ReconstructSome reconstructs only the wanted shards. shards[i] holds
+// symbols of shard i, symbol j belonging to codeword j, or is nil if
+// the shard is missing. Wanted shards that are missing are allocated
+// and filled in place. When few shards are wanted, each is computed as
+// a weighted sum of k available shards with Lagrange weights, otherwise
+// all erasures are recovered with transforms.
+func (c *Codec) ReconstructSome(shards [][]uint64, wanted []int) error {
+	_, err := c.reconstructSome(shards, wanted)
+	return err
+}
+
+// reconstructSome is ReconstructSome that also returns indices
+// of shards that are read.
+func (c *Codec) reconstructSome(shards [][]uint64, wanted []int) ([]int, error) {
+	if len(shards) != c.n {
+		return nil, fmt.Errorf("expected %d shards, got %d", c.n, len(shards))
+	}
+	size := -1
+	available, missing := []int{}, []int{}
+	for i, shard := range shards {
+		if shard == nil {
+			missing = append(missing, i)
+			continue
+		}
+		if size != -1 && len(shard) != size {
+			return nil, fmt.Errorf("shard %d length %d is expected to be %d", i, len(shard), size)
+		}
+		size = len(shard)
+		available = append(available, i)
+	}
+	if len(available) < c.k {
+		return nil, fmt.Errorf("not enough shards to reconstruct, %d of %d", len(available), c.k)
+	}
+	// shards are allocated only once nothing can fail, so that an error
+	// leaves shards untouched and missing ones are not taken as available
+	to := []int{}
+	seen := map[int]bool{}
+	for _, i := range wanted {
+		if i < 0 || i >= c.n {
+			return nil, fmt.Errorf("shard index %d is out of range", i)
+		}
+		if shards[i] == nil && !seen[i] {
+			seen[i] = true
+			to = append(to, i)
+		}
+	}
+	if len(to) == 0 {
+		return nil, nil
+	}
+	ensureDefaultBasis(c.m)
+
+	// weighted sum costs k multiplications per wanted symbol
+	// where recovery costs about three transforms per codeword
+	if c.k*len(to) <= c.n*c.m {
+		from := available[:c.k]
+		weights, err := c.interpolationWeights(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for w, i := range to {
+			shards[i] = make([]uint64, size)
+			for t, s := range from {
+				for j := 0; j < size; j++ {
+					shards[i][j] ^= mul64(weights[w][t], shards[s][j])
+				}
+			}
+		}
+		return from, nil
+	}
+
+	plan, err := c.NewDecodePlan(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range to {
+		shards[i] = make([]uint64, size)
+	}
+	var once sync.Once
+	var firstErr error
+	parallel(size, func(start, end int) {
+		codeword := make([]uint64, c.n)
+		for j := start; j < end; j++ {
+			for _, i := range available {
+				codeword[i] = shards[i][j]
+			}
+			if err := plan.Reconstruct(codeword); err != nil {
+				once.Do(func() { firstErr = err })
+				return
+			}
+			for _, i := range to {
+				shards[i][j] = codeword[i]
+			}
+		}
+	})
+	if firstErr != nil {
+		for _, i := range to {
+			shards[i] = nil
+		}
+		return nil, firstErr
+	}
+	return available, nil
+}
+
+// interpolationWeights returns weights such that value of a codeword at
+// to[w] is sum of weights[w][t] times its value at from[t], where from
+// has k points. With Z(x) vanishing at from, the weight is Lagrange
+// basis polynomial Z(x_w) / ((x_w - x_t) Z'(x_t)). Evaluations of Z and Z'
+// are taken over the domain with two transforms.
+func (c *Codec) interpolationWeights(from, to []int) ([][]uint64, error) {
+	G := defaultBasis().combinations
+	points := make([]uint64, len(from))
+	for t, i := range from {
+		points[t] = G[i]
+	}
+	Z, err := z(points)
+	if err != nil {
+		return nil, err
+	}
+	dZ := Z.derivative()
+	Z.expand(c.n)
+	dZ.expand(c.n)
+	if _, err := Z.fft(); err != nil {
+		return nil, err
+	}
+	if _, err := dZ.fft(); err != nil {
+		return nil, err
+	}
+	denom := newEmptyPoly(len(to) * len(from))
+	for w, i := range to {
+		for t, s := range from {
+			denom.a[w*len(from)+t] = mul64(points[t]^G[i], dZ.a[s])
+		}
+	}
+	if _, err := denom.invSample(); err != nil {
+		return nil, err
+	}
+	weights := make([][]uint64, len(to))
+	for w, i := range to {
+		weights[w] = denom.a[w*len(from) : (w+1)*len(from)]
+		for t := range weights[w] {
+			mulassign64(&weights[w][t], Z.a[i])
+		}
+	}
+	return weights, nil
+}
+
+func (c *Codec) erasureDecoder(missing []int) (*erasureDecoder, error) {
+	if len(missing) > c.n-c.k {
+		return nil, fmt.Errorf("too many missing shards, %d where at most %d is allowed", len(missing), c.n-c.k)
+	}
+	ensureDefaultBasis(c.m)
+	I := make([]uint64, len(missing))
+	for j, i := range missing {
+		if i < 0 || i >= c.n {
+			return nil, fmt.Errorf("shard index %d is out of range", i)
+		}
+		I[j] = uint64(i)
+	}
+	return newErasureDecoder(c.n, I, novelBasis)
+}
+
+var errInconsistentShards = errors.New("shards are inconsistent")
+
+// checkDegree checks that interpolated polynomial is a message, that is
+// surplus shards agree on a polynomial of degree less than k.
+func (c *Codec) checkDegree(p *poly) error {
+	for i := c.k; i < c.n; i++ {
+		if p.a[i] != 0 {
+			return errInconsistentShards
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package gf
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	n, k := 16, 6
+	c, err := NewCodec(n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := randPoly(k).a
+	codeword, err := c.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := []int{0, 3, 4, 7, 8, 9, 10, 15, 1, 2}
+	erased := append([]uint64{}, codeword...)
+	for _, i := range missing {
+		erased[i] = randGF64()
+	}
+	decoded, err := c.Decode(erased, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < k; i++ {
+		if decoded[i] != data[i] {
+			t.Fatal("decoding failed", i)
+		}
+	}
+	if _, err := c.Decode(codeword, append(missing, 5)); err == nil {
+		t.Fatal("too many missing shards must be rejected")
+	}
+	ok, err := c.Verify(codeword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("codeword must be consistent")
+	}
+
+	// surplus shards detect corruption
+	codeword[5] ^= 1
+	if _, err := c.Decode(codeword, missing[:5]); err == nil {
+		t.Fatal("inconsistent shards must be detected")
+	}
+	ok, err = c.Verify(codeword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("corrupt codeword must be inconsistent")
+	}
+}
+
+func TestCodecUpdateParity(t *testing.T) {
+	c, err := NewCodec(32, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := randPoly(20).a
+	codeword, err := c.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := randGF64()
+	if err := c.UpdateParity(codeword, 7, data[7], v); err != nil {
+		t.Fatal(err)
+	}
+	data[7] = v
+	expected, err := c.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range expected {
+		if expected[i] != codeword[i] {
+			t.Fatal("updated codeword must equal to fresh encoding")
+		}
+	}
+	if err := c.UpdateParity(codeword, 20, 0, 1); err == nil {
+		t.Fatal("index out of message must be rejected")
+	}
+}
+
+func TestNewCodecRejectsBadParameters(t *testing.T) {
+	for _, p := range [][2]int{{0, 0}, {1, 1}, {12, 4}, {8, 0}, {8, 9}, {2 * MaxShards, 4}} {
+		if _, err := NewCodec(p[0], p[1]); err == nil {
+			t.Fatal("bad parameters must be rejected", p)
+		}
+	}
+}
+
+// TestCodecConcurrentGrowth decodes while new codecs grow default basis,
+// run with -race to catch unsynchronized access to the basis.
+func TestCodecConcurrentGrowth(t *testing.T) {
+	initDefaultBasis(5)
+	c, err := NewCodec(32, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := randPoly(16).a
+	codeword, err := c.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := c.NewDecodePlan([]int{1, 4, 9, 16, 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started, done sync.WaitGroup
+	errs := make(chan error, 4)
+	for w := 0; w < 4; w++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			for i := 0; i < 200; i++ {
+				decoded, err := plan.Decode(codeword)
+				if err != nil {
+					errs <- err
+					return
+				}
+				for j := range data {
+					if decoded[j] != data[j] {
+						errs <- errors.New("decoding failed")
+						return
+					}
+				}
+			}
+		}()
+	}
+	started.Wait()
+	for m := 6; m <= 14; m++ {
+		if _, err := NewCodec(1<<m, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func codecShards(t testing.TB, c *Codec, size int) [][]uint64 {
+	shards := make([][]uint64, c.N())
+	for i := range shards {
+		shards[i] = make([]uint64, size)
+	}
+	for j := 0; j < size; j++ {
+		codeword, err := c.Encode(randPoly(c.K()).a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			shards[i][j] = codeword[i]
+		}
+	}
+	return shards
+}
+
+func TestCodecReconstructSome(t *testing.T) {
+	c, err := NewCodec(16, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := codecShards(t, c, 5)
+	for _, wanted := range [][]int{{3}, {3, 12}, {0, 3, 9, 12, 13, 14, 15, 4}} {
+		shards := append([][]uint64{}, original...)
+		for _, i := range []int{0, 3, 4, 9, 12, 13, 14, 15} {
+			shards[i] = nil
+		}
+		if err := c.ReconstructSome(shards, wanted); err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if shards[i] == nil {
+				continue
+			}
+			for j := range shards[i] {
+				if shards[i][j] != original[i][j] {
+					t.Fatal("bad reconstruction", wanted, i)
+				}
+			}
+		}
+		reconstructed := 0
+		for i := range shards {
+			if shards[i] != nil {
+				reconstructed++
+			}
+		}
+		for _, i := range wanted {
+			if shards[i] == nil {
+				t.Fatal("wanted shard is not reconstructed", i)
+			}
+		}
+		if reconstructed != 8+len(wanted) {
+			t.Fatal("only wanted shards must be reconstructed")
+		}
+	}
+	shards := append([][]uint64{}, original...)
+	for i := 0; i < 11; i++ {
+		shards[i] = nil
+	}
+	if err := c.ReconstructSome(shards, []int{0}); err == nil {
+		t.Fatal("reconstruction must fail with less than k shards")
+	}
+	// a failing call must not leave fake shards behind
+	shards = append([][]uint64{}, original...)
+	shards[3], shards[5] = nil, nil
+	if err := c.ReconstructSome(shards, []int{3, 5, 16}); err == nil {
+		t.Fatal("out of range index must be rejected")
+	}
+	if shards[3] != nil || shards[5] != nil {
+		t.Fatal("shards must be untouched on error")
+	}
+}
+
+func benchmarkReconstruct(b *testing.B, wanted int) {
+	n, k, size := 256, 128, 256
+	c, err := NewCodec(n, k)
+	if err != nil {
+		b.Fatal(err)
+	}
+	original := codecShards(b, c, size)
+	want := make([]int, wanted)
+	for i := range want {
+		want[i] = 2 * i
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shards := append([][]uint64{}, original...)
+		for j := 0; j < n-k; j++ {
+			shards[2*j] = nil
+		}
+		if err := c.ReconstructSome(shards, want); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCodecReconstructOne(b *testing.B) {
+	benchmarkReconstruct(b, 1)
+}
+
+func BenchmarkCodecReconstructFour(b *testing.B) {
+	benchmarkReconstruct(b, 4)
+}
+
+// BenchmarkCodecReconstructAll takes full recovery path which costs
+// the same regardless of the number of wanted shards.
+func BenchmarkCodecReconstructAll(b *testing.B) {
+	benchmarkReconstruct(b, 128)
+}
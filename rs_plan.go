@@ -0,0 +1,94 @@
+package gf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DecodePlan decodes codewords sharing the same missing shards. Erasure
+// locator Z(x), its evaluations and inverses of its derivative at missing
+// points are computed once when the plan is built, so that each codeword
+// costs three transforms. A plan is safe for concurrent use.
+type DecodePlan struct {
+	c       *Codec
+	missing []int
+	d       *erasureDecoder
+}
+
+// NewDecodePlan builds a plan for codewords with symbols at missing indices erased.
+func (c *Codec) NewDecodePlan(missing []int) (*DecodePlan, error) {
+	d, err := c.erasureDecoder(missing)
+	if err != nil {
+		return nil, err
+	}
+	return &DecodePlan{c, append([]int{}, missing...), d}, nil
+}
+
+// Missing returns the missing indices the plan is built for.
+func (p *DecodePlan) Missing() []int {
+	return append([]int{}, p.missing...)
+}
+
+// Reconstruct fills missing symbols of a codeword in place.
+func (p *DecodePlan) Reconstruct(codeword []uint64) error {
+	if len(codeword) != p.c.n {
+		return fmt.Errorf("codeword length %d is expected to be %d", len(codeword), p.c.n)
+	}
+	return p.d.complete(newPoly(codeword))
+}
+
+// Decode returns message of a codeword. Values of missing symbols are
+// ignored and codeword is not modified.
+func (p *DecodePlan) Decode(codeword []uint64) ([]uint64, error) {
+	if len(codeword) != p.c.n {
+		return nil, fmt.Errorf("codeword length %d is expected to be %d", len(codeword), p.c.n)
+	}
+	q := newEmptyPoly(p.c.n)
+	copy(q.a, codeword)
+	if err := p.d.complete(q); err != nil {
+		return nil, err
+	}
+	if _, err := q.lifft(); err != nil {
+		return nil, err
+	}
+	if err := p.c.checkDegree(q); err != nil {
+		return nil, err
+	}
+	return q.a[:p.c.k], nil
+}
+
+// DecodeAll decodes codewords concurrently and returns their messages.
+func (p *DecodePlan) DecodeAll(codewords [][]uint64) ([][]uint64, error) {
+	messages := make([][]uint64, len(codewords))
+	var once sync.Once
+	var firstErr error
+	parallel(len(codewords), func(start, end int) {
+		for j := start; j < end; j++ {
+			m, err := p.Decode(codewords[j])
+			if err != nil {
+				once.Do(func() { firstErr = fmt.Errorf("codeword %d: %w", j, err) })
+				return
+			}
+			messages[j] = m
+		}
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return messages, nil
+}
+
+// ReconstructAll fills missing symbols of codewords concurrently.
+func (p *DecodePlan) ReconstructAll(codewords [][]uint64) error {
+	var once sync.Once
+	var firstErr error
+	parallel(len(codewords), func(start, end int) {
+		for j := start; j < end; j++ {
+			if err := p.Reconstruct(codewords[j]); err != nil {
+				once.Do(func() { firstErr = fmt.Errorf("codeword %d: %w", j, err) })
+				return
+			}
+		}
+	})
+	return firstErr
+}
@@ -0,0 +1,99 @@
+package gf
+
+import (
+	"testing"
+)
+
+func TestDecodePlan(t *testing.T) {
+	c, err := NewCodec(32, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := []int{0, 1, 5, 8, 13, 21, 30}
+	plan, err := c.NewDecodePlan(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make([][]uint64, 100)
+	codewords := make([][]uint64, len(messages))
+	for j := range messages {
+		messages[j] = randPoly(20).a
+		if codewords[j], err = c.Encode(messages[j]); err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range missing {
+			codewords[j][i] = 0
+		}
+	}
+	decoded, err := plan.DecodeAll(codewords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := range messages {
+		for t0 := range messages[j] {
+			if decoded[j][t0] != messages[j][t0] {
+				t.Fatal("bad decoding", j)
+			}
+		}
+	}
+	if err := plan.ReconstructAll(codewords); err != nil {
+		t.Fatal(err)
+	}
+	for j := range codewords {
+		ok, err := c.Verify(codewords[j])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("reconstructed codeword must be consistent", j)
+		}
+	}
+	codewords[42][3] ^= 1
+	if _, err := plan.DecodeAll(codewords); err == nil {
+		t.Fatal("inconsistent codeword must be reported")
+	}
+}
+
+func benchmarkDecodeCodewords(b *testing.B, withPlan bool) {
+	n, k := 256, 192
+	c, err := NewCodec(n, k)
+	if err != nil {
+		b.Fatal(err)
+	}
+	missing := []int{}
+	for i := 0; i < n-k; i++ {
+		missing = append(missing, 3*i+1)
+	}
+	codewords := make([][]uint64, 256)
+	for j := range codewords {
+		if codewords[j], err = c.Encode(randPoly(k).a); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if withPlan {
+			plan, err := c.NewDecodePlan(missing)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := plan.DecodeAll(codewords); err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+		for j := range codewords {
+			if _, err := c.Decode(codewords[j], missing); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeWithoutPlan(b *testing.B) {
+	benchmarkDecodeCodewords(b, false)
+}
+
+func BenchmarkDecodeWithPlan(b *testing.B) {
+	benchmarkDecodeCodewords(b, true)
+}
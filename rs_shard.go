@@ -0,0 +1,497 @@
+package gf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Shard file layout, all integers in little endian:
+//
+//	header
+//	  0  magic "GFRS"
+//	  4  version, uint16
+//	  6  symbol size in bytes, uint16
+//	  8  n, uint32
+//	 12  k, uint32
+//	 16  basis generator, uint64
+//	 24  stripe size in bytes, uint32
+//	 28  shard index, uint32
+//	 32  original length in bytes, uint64
+//	 40  CRC-32C of the header, uint32
+//	stripes
+//	  stripe size bytes of shard data followed by its CRC-32C, uint32
+//
+// Number of stripes is derived from the original length so that
+// a truncated shard file is detected.
+
+const (
+	shardMagic        = "GFRS"
+	shardVersion      = 1
+	shardHeaderSize   = 44
+	shardChecksumSize = 4
+	shardSymbolSize   = 8
+)
+
+// ErrCorruptStripe is returned by ShardReader when a stripe fails its checksum.
+var ErrCorruptStripe = errors.New("corrupt stripe")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ShardHeader describes a shard file.
+type ShardHeader struct {
+	Version        int
+	SymbolSize     int
+	N              int
+	K              int
+	BasisGenerator uint64
+	StripeSize     int
+	Index          int
+	Length         int64
+}
+
+func (h *ShardHeader) validate() error {
+	if h.Version != shardVersion {
+		return fmt.Errorf("unsupported shard version: %d", h.Version)
+	}
+	if h.SymbolSize != shardSymbolSize {
+		return fmt.Errorf("unsupported symbol size: %d", h.SymbolSize)
+	}
+	if h.BasisGenerator != defaultBasisGenerator {
+		return fmt.Errorf("unsupported basis generator: %#x", h.BasisGenerator)
+	}
+	// header is checked against bad input as well as bit rot, so that
+	// a crafted header does not make the codec exhaust memory
+	if h.N < 2 || h.N&(h.N-1) != 0 || h.N > MaxShards || h.K < 1 || h.K > h.N {
+		return fmt.Errorf("bad code parameters n = %d, k = %d", h.N, h.K)
+	}
+	if h.Index < 0 || h.Index >= h.N {
+		return fmt.Errorf("shard index %d is out of range", h.Index)
+	}
+	if h.Length < 0 {
+		return fmt.Errorf("negative length: %d", h.Length)
+	}
+	if err := checkStripeSize(h.StripeSize); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stripes returns number of stripes in each shard.
+func (h *ShardHeader) Stripes() int64 {
+	s := int64(h.K) * int64(h.StripeSize)
+	return (h.Length + s - 1) / s
+}
+
+// sameObject checks if two headers belong to shards of the same object.
+func (h *ShardHeader) sameObject(o *ShardHeader) bool {
+	g := *o
+	g.Index = h.Index
+	return *h == g
+}
+
+func (h *ShardHeader) marshal() []byte {
+	b := make([]byte, shardHeaderSize)
+	copy(b, shardMagic)
+	binary.LittleEndian.PutUint16(b[4:], uint16(h.Version))
+	binary.LittleEndian.PutUint16(b[6:], uint16(h.SymbolSize))
+	binary.LittleEndian.PutUint32(b[8:], uint32(h.N))
+	binary.LittleEndian.PutUint32(b[12:], uint32(h.K))
+	binary.LittleEndian.PutUint64(b[16:], h.BasisGenerator)
+	binary.LittleEndian.PutUint32(b[24:], uint32(h.StripeSize))
+	binary.LittleEndian.PutUint32(b[28:], uint32(h.Index))
+	binary.LittleEndian.PutUint64(b[32:], uint64(h.Length))
+	binary.LittleEndian.PutUint32(b[40:], crc32.Checksum(b[:40], castagnoli))
+	return b
+}
+
+func unmarshalShardHeader(b []byte) (*ShardHeader, error) {
+	if string(b[:4]) != shardMagic {
+		return nil, errors.New("not a shard file")
+	}
+	if crc32.Checksum(b[:40], castagnoli) != binary.LittleEndian.Uint32(b[40:]) {
+		return nil, errors.New("corrupt shard header")
+	}
+	h := &ShardHeader{
+		Version:        int(binary.LittleEndian.Uint16(b[4:])),
+		SymbolSize:     int(binary.LittleEndian.Uint16(b[6:])),
+		N:              int(binary.LittleEndian.Uint32(b[8:])),
+		K:              int(binary.LittleEndian.Uint32(b[12:])),
+		BasisGenerator: binary.LittleEndian.Uint64(b[16:]),
+		StripeSize:     int(binary.LittleEndian.Uint32(b[24:])),
+		Index:          int(binary.LittleEndian.Uint32(b[28:])),
+		Length:         int64(binary.LittleEndian.Uint64(b[32:])),
+	}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ShardWriter writes shard data into a shard file. Header is written
+// at construction and each stripe is followed by its checksum.
+type ShardWriter struct {
+	w       io.Writer
+	h       ShardHeader
+	buf     []byte
+	stripes int64
+}
+
+// NewShardWriter writes the header to w and returns a writer for shard data.
+// Version, symbol size and basis generator are filled if left zero.
+func NewShardWriter(w io.Writer, h ShardHeader) (*ShardWriter, error) {
+	if h.Version == 0 {
+		h.Version = shardVersion
+	}
+	if h.SymbolSize == 0 {
+		h.SymbolSize = shardSymbolSize
+	}
+	if h.BasisGenerator == 0 {
+		h.BasisGenerator = defaultBasisGenerator
+	}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(h.marshal()); err != nil {
+		return nil, err
+	}
+	return &ShardWriter{w: w, h: h, buf: make([]byte, 0, h.StripeSize+shardChecksumSize)}, nil
+}
+
+// Header returns header of the shard.
+func (s *ShardWriter) Header() ShardHeader {
+	return s.h
+}
+
+// Write buffers shard data and writes each completed stripe with its checksum.
+func (s *ShardWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if s.stripes == s.h.Stripes() {
+			return written, errors.New("shard data exceeds the length in header")
+		}
+		l := s.h.StripeSize - len(s.buf)
+		if l > len(p) {
+			l = len(p)
+		}
+		s.buf = append(s.buf, p[:l]...)
+		p = p[l:]
+		written += l
+		if len(s.buf) == s.h.StripeSize {
+			s.buf = binary.LittleEndian.AppendUint32(s.buf, crc32.Checksum(s.buf, castagnoli))
+			if _, err := s.w.Write(s.buf); err != nil {
+				return written, err
+			}
+			s.buf = s.buf[:0]
+			s.stripes++
+		}
+	}
+	return written, nil
+}
+
+// Close checks that all stripes are written. Underlying writer is not closed.
+func (s *ShardWriter) Close() error {
+	if len(s.buf) != 0 || s.stripes != s.h.Stripes() {
+		return fmt.Errorf("shard %d is incomplete, %d of %d stripes are written", s.h.Index, s.stripes, s.h.Stripes())
+	}
+	return nil
+}
+
+// ShardReader reads shard data from a shard file verifying stripe checksums.
+type ShardReader struct {
+	r       io.Reader
+	h       *ShardHeader
+	buf     []byte
+	off     int
+	stripes int64
+}
+
+// NewShardReader reads and validates the header of a shard file.
+func NewShardReader(r io.Reader) (*ShardReader, error) {
+	b := make([]byte, shardHeaderSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	h, err := unmarshalShardHeader(b)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, h.StripeSize+shardChecksumSize)
+	return &ShardReader{r: r, h: h, buf: buf, off: len(buf)}, nil
+}
+
+// Header returns header of the shard.
+func (s *ShardReader) Header() ShardHeader {
+	return *s.h
+}
+
+// Read reads shard data. If a stripe fails its checksum, the whole stripe
+// is skipped and an error wrapping ErrCorruptStripe is returned, so that
+// the next read continues from the following stripe.
+func (s *ShardReader) Read(p []byte) (int, error) {
+	if s.off == len(s.buf) {
+		if s.stripes == s.h.Stripes() {
+			return 0, io.EOF
+		}
+		if _, err := io.ReadFull(s.r, s.buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		s.stripes++
+		s.off = 0
+		data := s.buf[:s.h.StripeSize]
+		if crc32.Checksum(data, castagnoli) != binary.LittleEndian.Uint32(s.buf[s.h.StripeSize:]) {
+			s.off = len(s.buf)
+			return 0, fmt.Errorf("%w %d of shard %d", ErrCorruptStripe, s.stripes-1, s.h.Index)
+		}
+	}
+	l := copy(p, s.buf[s.off:s.h.StripeSize])
+	s.off += l
+	if s.off == s.h.StripeSize {
+		s.off = len(s.buf)
+	}
+	return l, nil
+}
+
+// EncodeShards encodes size bytes of r into shard files written to shards.
+func (e *StreamEncoder) EncodeShards(r io.Reader, size int64, shards []io.Writer) error {
+	if len(shards) != e.c.n {
+		return fmt.Errorf("expected %d shard writers, got %d", e.c.n, len(shards))
+	}
+	ws := make([]*ShardWriter, e.c.n)
+	ios := make([]io.Writer, e.c.n)
+	for i := 0; i < e.c.n; i++ {
+		var err error
+		ws[i], err = NewShardWriter(shards[i], ShardHeader{
+			N:          e.c.n,
+			K:          e.c.k,
+			StripeSize: e.stripeSize,
+			Index:      i,
+			Length:     size,
+		})
+		if err != nil {
+			return err
+		}
+		ios[i] = ws[i]
+	}
+	total, err := e.Encode(io.LimitReader(r, size), ios)
+	if err != nil {
+		return err
+	}
+	if total != size {
+		return fmt.Errorf("expected %d bytes, read %d", size, total)
+	}
+	for i := 0; i < e.c.n; i++ {
+		if err := ws[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShardSet is a set of shard files of an object opened for a single pass
+// of decoding, verification or repair. Shards are placed by index in their
+// headers. nil readers and shards with unreadable headers are taken as
+// missing and corrupt stripes are taken as erasures.
+type ShardSet struct {
+	h       *ShardHeader
+	readers []io.Reader
+	d       *StreamDecoder
+	// Progress if set is called after each stripe is processed.
+	Progress func(done, total int64)
+}
+
+// OpenShards reads headers of shard files given in any order.
+func OpenShards(shards []io.Reader) (*ShardSet, error) {
+	var h *ShardHeader
+	var readers []io.Reader
+	for _, r := range shards {
+		if r == nil {
+			continue
+		}
+		s, err := NewShardReader(r)
+		if err != nil {
+			continue
+		}
+		if h == nil {
+			h = s.h
+			readers = make([]io.Reader, h.N)
+		} else if !h.sameObject(s.h) {
+			return nil, fmt.Errorf("shard %d does not belong to the same object", s.h.Index)
+		}
+		if readers[s.h.Index] != nil {
+			return nil, fmt.Errorf("shard %d is repeated", s.h.Index)
+		}
+		readers[s.h.Index] = s
+	}
+	if h == nil {
+		return nil, errors.New("no readable shard")
+	}
+	c, err := NewCodec(h.N, h.K)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewStreamDecoder(c, h.StripeSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ShardSet{h: h, readers: readers, d: d}, nil
+}
+
+// Header returns header of the set with index of the first readable shard.
+func (s *ShardSet) Header() ShardHeader {
+	return *s.h
+}
+
+// Missing returns indices of shards that are not given or unreadable.
+func (s *ShardSet) Missing() []int {
+	missing := []int{}
+	for i, r := range s.readers {
+		if r == nil {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+func (s *ShardSet) decodeStripes(fn func(st *stripe, err error) error) error {
+	total := s.h.Stripes()
+	return s.d.decodeStripes(s.readers, total, func(st *stripe, err error) error {
+		if err := fn(st, err); err != nil {
+			return err
+		}
+		if s.Progress != nil {
+			s.Progress(st.index+1, total)
+		}
+		return nil
+	})
+}
+
+// stripeError reports a stripe that can not be decoded.
+func stripeError(st *stripe, err error) error {
+	return fmt.Errorf("stripe %d: %w", st.index, err)
+}
+
+// Decode writes the original object to w.
+func (s *ShardSet) Decode(w io.Writer) error {
+	size := s.h.Length
+	return s.decodeStripes(func(st *stripe, err error) error {
+		if err != nil {
+			return stripeError(st, err)
+		}
+		l := int64(len(st.data))
+		if size < l {
+			l = size
+		}
+		if _, err := w.Write(st.data[:l]); err != nil {
+			return err
+		}
+		size -= l
+		return nil
+	})
+}
+
+// ShardReport is the result of verification of a shard set.
+type ShardReport struct {
+	// Stripes is number of stripes in each shard.
+	Stripes int64
+	// Damaged lists shards that are missing or have an unreadable
+	// or corrupt stripe, that is the shards to be repaired.
+	Damaged []int
+	// Unrecoverable lists stripes with less than k readable shards.
+	Unrecoverable []int64
+	// Inconsistent lists stripes whose shards pass checksums but
+	// do not agree on a codeword.
+	Inconsistent []int64
+}
+
+// Healthy returns true if all shards are intact and consistent.
+func (r *ShardReport) Healthy() bool {
+	return len(r.Damaged) == 0 && r.Recoverable()
+}
+
+// Recoverable returns true if every stripe can be decoded.
+func (r *ShardReport) Recoverable() bool {
+	return len(r.Unrecoverable) == 0 && len(r.Inconsistent) == 0
+}
+
+// Verify reads every stripe and checks that shards are readable and
+// agree on a codeword. Agreement is checked with redundancy left after
+// erasures, so a stripe with n - k erasures is not checked.
+func (s *ShardSet) Verify() (*ShardReport, error) {
+	r := &ShardReport{Stripes: s.h.Stripes()}
+	damaged := make([]bool, s.h.N)
+	err := s.decodeStripes(func(st *stripe, err error) error {
+		for i, e := range st.erased {
+			damaged[i] = damaged[i] || e
+		}
+		switch err {
+		case errNotEnoughShards:
+			r.Unrecoverable = append(r.Unrecoverable, st.index)
+		case errInconsistentShards:
+			r.Inconsistent = append(r.Inconsistent, st.index)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, sr := range s.readers {
+		if damaged[i] || sr == nil {
+			r.Damaged = append(r.Damaged, i)
+		}
+	}
+	return r, nil
+}
+
+// Repair writes regenerated shard files of the shards at given indices.
+// Typically these are damaged shards found by Verify with a previous
+// pass over the same shard files.
+func (s *ShardSet) Repair(shards map[int]io.Writer) error {
+	ws := make(map[int]*ShardWriter, len(shards))
+	for i, w := range shards {
+		h := *s.h
+		h.Index = i
+		sw, err := NewShardWriter(w, h)
+		if err != nil {
+			return err
+		}
+		ws[i] = sw
+	}
+	err := s.decodeStripes(func(st *stripe, err error) error {
+		if err != nil {
+			return stripeError(st, err)
+		}
+		for i, w := range ws {
+			if _, err := w.Write(st.shards[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, w := range ws {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeShards reconstructs the original object from a set of shard files.
+func DecodeShards(shards []io.Reader, w io.Writer) (*ShardHeader, error) {
+	s, err := OpenShards(shards)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Decode(w); err != nil {
+		return nil, err
+	}
+	return s.h, nil
+}
@@ -0,0 +1,254 @@
+package gf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+func encodeShardFiles(t *testing.T, n, k, stripeSize int, input []byte) [][]byte {
+	c, err := NewCodec(n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewStreamEncoder(c, stripeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bufs := make([]*bytes.Buffer, n)
+	ws := make([]io.Writer, n)
+	for i := range bufs {
+		bufs[i] = new(bytes.Buffer)
+		ws[i] = bufs[i]
+	}
+	if err := e.EncodeShards(bytes.NewReader(input), int64(len(input)), ws); err != nil {
+		t.Fatal(err)
+	}
+	files := make([][]byte, n)
+	for i := range bufs {
+		files[i] = bufs[i].Bytes()
+	}
+	return files
+}
+
+func TestShardHeader(t *testing.T) {
+	h := &ShardHeader{shardVersion, shardSymbolSize, 16, 11, defaultBasisGenerator, 4096, 7, 1<<40 + 3}
+	g, err := unmarshalShardHeader(h.marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *g != *h {
+		t.Fatal("header round trip failed")
+	}
+	b := h.marshal()
+	b[20] ^= 1
+	if _, err := unmarshalShardHeader(b); err == nil {
+		t.Fatal("corrupt header must be rejected")
+	}
+	// checksum guards against bit rot but not against bad parameters
+	g = &ShardHeader{shardVersion, shardSymbolSize, 1 << 31, 11, defaultBasisGenerator, 4096, 7, 1 << 40}
+	if _, err := unmarshalShardHeader(g.marshal()); err == nil {
+		t.Fatal("too many shards must be rejected")
+	}
+	g.N = 1 << 32
+	if _, err := NewShardWriter(io.Discard, *g); err == nil {
+		t.Fatal("number of shards not fitting in 32 bits must be rejected")
+	}
+	g.N, g.StripeSize = 16, 1<<32+8
+	if _, err := NewShardWriter(io.Discard, *g); err == nil {
+		t.Fatal("stripe size not fitting in 32 bits must be rejected")
+	}
+	g.StripeSize = MaxStripeSize + 8
+	if _, err := unmarshalShardHeader(g.marshal()); err == nil {
+		t.Fatal("stripe size exceeding MaxStripeSize must be rejected")
+	}
+	c, err := NewCodec(16, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStreamEncoder(c, MaxStripeSize+8); err == nil {
+		t.Fatal("stream encoder must reject stripe size exceeding MaxStripeSize")
+	}
+}
+
+func TestShardFiles(t *testing.T) {
+	n, k, stripeSize := 8, 5, 32
+	input := make([]byte, 1234)
+	_, _ = rand.Read(input)
+	files := encodeShardFiles(t, n, k, stripeSize, input)
+	stripes := (len(input) + k*stripeSize - 1) / (k * stripeSize)
+	for i := range files {
+		if len(files[i]) != shardHeaderSize+stripes*(stripeSize+shardChecksumSize) {
+			t.Fatal("bad shard file length", i)
+		}
+	}
+
+	// corrupt a different stripe in each of three shards and
+	// one more stripe in one of them, drop one shard and shuffle the rest
+	corrupt := func(i, stripe int) {
+		files[i][shardHeaderSize+stripe*(stripeSize+shardChecksumSize)+3] ^= 0x10
+	}
+	corrupt(0, 0)
+	corrupt(2, 1)
+	corrupt(5, 2)
+	corrupt(0, 3)
+	rs := []io.Reader{nil}
+	for _, i := range []int{7, 3, 0, 6, 2, 5, 4} {
+		rs = append(rs, bytes.NewReader(files[i]))
+	}
+	out := new(bytes.Buffer)
+	h, err := DecodeShards(rs, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.N != n || h.K != k || h.Length != int64(len(input)) {
+		t.Fatal("bad header")
+	}
+	if !bytes.Equal(out.Bytes(), input) {
+		t.Fatal("decoding shard files failed")
+	}
+
+	// reader reports the corrupt stripe and continues
+	s, err := NewShardReader(bytes.NewReader(files[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, stripeSize)
+	if _, err := io.ReadFull(s, buf); !errors.Is(err, ErrCorruptStripe) {
+		t.Fatal("corrupt stripe expected", err)
+	}
+	if _, err := io.ReadFull(s, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// four erasures in the same stripe are too many
+	corrupt(3, 1)
+	corrupt(4, 1)
+	rs = rs[:0]
+	for i := 0; i < n; i++ {
+		rs = append(rs, bytes.NewReader(files[i]))
+	}
+	rs[7] = nil
+	if _, err := DecodeShards(rs, io.Discard); err == nil {
+		t.Fatal("decoding must fail with too many erasures in a stripe")
+	}
+}
+
+func TestShardFilesTruncated(t *testing.T) {
+	n, k, stripeSize := 4, 2, 16
+	input := make([]byte, 100)
+	_, _ = rand.Read(input)
+	files := encodeShardFiles(t, n, k, stripeSize, input)
+	rs := make([]io.Reader, n)
+	for i := range rs {
+		rs[i] = bytes.NewReader(files[i])
+	}
+	rs[1] = bytes.NewReader(files[1][:len(files[1])-5])
+	rs[2] = bytes.NewReader(files[2][:10])
+	out := new(bytes.Buffer)
+	if _, err := DecodeShards(rs, out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), input) {
+		t.Fatal("decoding shard files failed")
+	}
+}
+
+func openShardFiles(t *testing.T, files [][]byte) *ShardSet {
+	rs := make([]io.Reader, len(files))
+	for i := range files {
+		if files[i] != nil {
+			rs[i] = bytes.NewReader(files[i])
+		}
+	}
+	s, err := OpenShards(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestShardVerifyAndRepair(t *testing.T) {
+	n, k, stripeSize := 8, 4, 24
+	input := make([]byte, 777)
+	_, _ = rand.Read(input)
+	files := encodeShardFiles(t, n, k, stripeSize, input)
+	original := make([][]byte, n)
+	for i := range files {
+		original[i] = append([]byte{}, files[i]...)
+	}
+	r, err := openShardFiles(t, files).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Healthy() {
+		t.Fatal("fresh shards must be healthy")
+	}
+
+	offset := func(stripe int) int {
+		return shardHeaderSize + stripe*(stripeSize+shardChecksumSize)
+	}
+	files[1] = nil
+	files[6][offset(2)] ^= 1
+	files[3] = files[3][:offset(5)+7]
+	r, err = openShardFiles(t, files).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Recoverable() || r.Healthy() {
+		t.Fatal("shards must be damaged but recoverable")
+	}
+	if fmt.Sprint(r.Damaged) != "[1 3 6]" {
+		t.Fatal("bad damaged shards", r.Damaged)
+	}
+
+	repaired := map[int]*bytes.Buffer{}
+	ws := map[int]io.Writer{}
+	for _, i := range r.Damaged {
+		repaired[i] = new(bytes.Buffer)
+		ws[i] = repaired[i]
+	}
+	if err := openShardFiles(t, files).Repair(ws); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range repaired {
+		if !bytes.Equal(b.Bytes(), original[i]) {
+			t.Fatal("repaired shard must equal to the original", i)
+		}
+	}
+
+	// a stripe rewritten with a valid checksum is inconsistent
+	files = append([][]byte{}, original...)
+	files[5] = append([]byte{}, original[5]...)
+	stripe := files[5][offset(1) : offset(1)+stripeSize]
+	stripe[0] ^= 1
+	binary.LittleEndian.PutUint32(files[5][offset(1)+stripeSize:], crc32.Checksum(stripe, castagnoli))
+	r, err = openShardFiles(t, files).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(r.Inconsistent) != "[1]" || r.Recoverable() {
+		t.Fatal("inconsistent stripe must be reported", r.Inconsistent)
+	}
+	files[0], files[2], files[4], files[7] = nil, nil, nil, nil
+	r, err = openShardFiles(t, files).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Unrecoverable) != 0 || len(r.Inconsistent) != 0 {
+		t.Fatal("with n - k erasures, consistency can not be checked")
+	}
+	files[1] = nil
+	r, err = openShardFiles(t, files).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(r.Unrecoverable)) != r.Stripes {
+		t.Fatal("all stripes must be unrecoverable")
+	}
+}
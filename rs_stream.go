@@ -0,0 +1,318 @@
+package gf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Streams are processed in stripes. A stripe of a shard stream is stripeSize
+// bytes and holds one symbol of each of stripeSize / 8 codewords, so that
+// a stripe of input is k * stripeSize bytes. Symbols are encoded in
+// little endian and message symbols of a codeword are consecutive in input.
+// Memory use is bounded with n stripes regardless of the object size.
+
+// MaxStripeSize is the maximum stripe size in bytes, bounding a stripe
+// of input at MaxStripeSize * k bytes.
+const MaxStripeSize = 1 << 26
+
+func checkStripeSize(stripeSize int) error {
+	if stripeSize < 8 || stripeSize%8 != 0 {
+		return fmt.Errorf("stripe size is expected to be a positive multiple of 8: %d", stripeSize)
+	}
+	if stripeSize > MaxStripeSize {
+		return fmt.Errorf("stripe size %d exceeds %d", stripeSize, MaxStripeSize)
+	}
+	return nil
+}
+
+// StreamEncoder encodes a byte stream into n shard streams.
+type StreamEncoder struct {
+	c          *Codec
+	stripeSize int
+}
+
+// NewStreamEncoder returns a stream encoder writing stripeSize bytes
+// to each shard per stripe.
+func NewStreamEncoder(c *Codec, stripeSize int) (*StreamEncoder, error) {
+	if err := checkStripeSize(stripeSize); err != nil {
+		return nil, err
+	}
+	ensureDefaultBasis(c.m)
+	return &StreamEncoder{c, stripeSize}, nil
+}
+
+// Encode reads r until EOF and writes shard i to shards[i].
+// Last stripe is padded with zeros. It returns number of bytes read
+// which is required to trim the padding at decoding.
+func (e *StreamEncoder) Encode(r io.Reader, shards []io.Writer) (int64, error) {
+	n, k := e.c.n, e.c.k
+	if len(shards) != n {
+		return 0, fmt.Errorf("expected %d shard writers, got %d", n, len(shards))
+	}
+	words := e.stripeSize / 8
+	in := make([]byte, k*e.stripeSize)
+	out := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		out[i] = make([]byte, e.stripeSize)
+	}
+	var total int64
+	for {
+		l, err := io.ReadFull(r, in)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return total, err
+		}
+		total += int64(l)
+		for i := l; i < len(in); i++ {
+			in[i] = 0
+		}
+		var once sync.Once
+		var stripeErr error
+		parallel(words, func(start, end int) {
+			if err := e.encodeWords(in, out, start, end); err != nil {
+				once.Do(func() { stripeErr = err })
+			}
+		})
+		if stripeErr != nil {
+			return total, stripeErr
+		}
+		for i := 0; i < n; i++ {
+			if _, err := shards[i].Write(out[i]); err != nil {
+				return total, fmt.Errorf("writing shard %d: %w", i, err)
+			}
+		}
+		if l < len(in) {
+			return total, nil
+		}
+	}
+}
+
+// encodeWords encodes codewords of a stripe in [start, end) from input
+// stripe in to output stripes of shards.
+func (e *StreamEncoder) encodeWords(in []byte, out [][]byte, start, end int) error {
+	n, k := e.c.n, e.c.k
+	codeword := newEmptyPoly(n)
+	for j := start; j < end; j++ {
+		for t := 0; t < k; t++ {
+			codeword.a[t] = binary.LittleEndian.Uint64(in[(j*k+t)*8:])
+		}
+		for i := k; i < n; i++ {
+			codeword.a[i] = 0
+		}
+		if _, err := codeword.lfft(); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint64(out[i][j*8:], codeword.a[i])
+		}
+	}
+	return nil
+}
+
+// StreamDecoder reconstructs a byte stream from shard streams.
+type StreamDecoder struct {
+	c          *Codec
+	stripeSize int
+}
+
+// NewStreamDecoder returns a stream decoder for shards written
+// by a stream encoder with the same codec and stripe size.
+func NewStreamDecoder(c *Codec, stripeSize int) (*StreamDecoder, error) {
+	if err := checkStripeSize(stripeSize); err != nil {
+		return nil, err
+	}
+	return &StreamDecoder{c, stripeSize}, nil
+}
+
+// Decode writes size bytes of the original stream to w. shards[i] is
+// stream of shard i or nil if the shard is missing. A shard failing to
+// read is dropped and decoding continues as long as k shards remain.
+// An error wrapping ErrCorruptStripe erases only the current stripe
+// of the shard and reading continues with the next stripe.
+func (d *StreamDecoder) Decode(shards []io.Reader, w io.Writer, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("negative size: %d", size)
+	}
+	return d.decodeStripes(shards, d.stripes(size), func(s *stripe, err error) error {
+		if err != nil {
+			return err
+		}
+		l := int64(len(s.data))
+		if size < l {
+			l = size
+		}
+		if _, err := w.Write(s.data[:l]); err != nil {
+			return err
+		}
+		size -= l
+		return nil
+	})
+}
+
+// Verify reads shard streams of size bytes of original data and returns
+// indices of inconsistent stripes. With all shards present, each codeword
+// costs a single inverse transform. Missing shards and corrupt stripes are
+// taken as erasures and consistency is checked with remaining redundancy.
+func (d *StreamDecoder) Verify(shards []io.Reader, size int64) ([]int64, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("negative size: %d", size)
+	}
+	inconsistent := []int64{}
+	err := d.decodeStripes(shards, d.stripes(size), func(s *stripe, err error) error {
+		switch err {
+		case nil:
+		case errInconsistentShards:
+			inconsistent = append(inconsistent, s.index)
+		default:
+			return fmt.Errorf("stripe %d: %w", s.index, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inconsistent, nil
+}
+
+// stripes returns number of stripes that size bytes are encoded into.
+func (d *StreamDecoder) stripes(size int64) int64 {
+	s := int64(d.c.k) * int64(d.stripeSize)
+	return (size + s - 1) / s
+}
+
+// maxCachedPlans bounds number of decode plans kept while decoding a stream.
+const maxCachedPlans = 64
+
+var errNotEnoughShards = errors.New("not enough shards to reconstruct")
+
+// stripe is a decoded stripe. Erased stripes of shards are reconstructed
+// in place so that shards hold the whole stripe of the codewords.
+type stripe struct {
+	index  int64
+	erased []bool
+	shards [][]byte
+	data   []byte
+}
+
+// decodeStripes decodes count stripes and passes each to fn, together with
+// errNotEnoughShards or errInconsistentShards if the stripe can not be
+// decoded. Decoding stops at the first error fn returns. Buffers of
+// a stripe are reused for the next one.
+func (d *StreamDecoder) decodeStripes(shards []io.Reader, count int64, fn func(s *stripe, err error) error) error {
+	n, k := d.c.n, d.c.k
+	if len(shards) != n {
+		return fmt.Errorf("expected %d shard readers, got %d", n, len(shards))
+	}
+	shards = append([]io.Reader{}, shards...)
+	words := d.stripeSize / 8
+	s := &stripe{
+		erased: make([]bool, n),
+		shards: make([][]byte, n),
+		data:   make([]byte, k*d.stripeSize),
+	}
+	for i := 0; i < n; i++ {
+		s.shards[i] = make([]byte, d.stripeSize)
+	}
+	// plans are kept for erasure patterns seen, as corrupt stripes
+	// of a shard alternate between a few patterns
+	plans := map[string]*DecodePlan{}
+	var plan *DecodePlan
+	for ; s.index < count; s.index++ {
+		changed := plan == nil
+		for i := 0; i < n; i++ {
+			lost := shards[i] == nil
+			if !lost {
+				if _, err := io.ReadFull(shards[i], s.shards[i]); err != nil {
+					// a corrupt stripe is an erasure of this stripe only
+					if !errors.Is(err, ErrCorruptStripe) {
+						shards[i] = nil
+					}
+					lost = true
+				}
+			}
+			if s.erased[i] != lost {
+				s.erased[i] = lost
+				changed = true
+			}
+		}
+		if changed {
+			missing := []int{}
+			for i := 0; i < n; i++ {
+				if s.erased[i] {
+					missing = append(missing, i)
+				}
+			}
+			plan = nil
+			if len(missing) > n-k {
+				if err := fn(s, errNotEnoughShards); err != nil {
+					return err
+				}
+				continue
+			}
+			key := fmt.Sprint(missing)
+			if plan = plans[key]; plan == nil {
+				var err error
+				if plan, err = d.c.NewDecodePlan(missing); err != nil {
+					return err
+				}
+				if len(plans) == maxCachedPlans {
+					plans = map[string]*DecodePlan{}
+				}
+				plans[key] = plan
+			}
+		}
+		var mu sync.Mutex
+		var stripeErr error
+		parallel(words, func(start, end int) {
+			if err := d.decodeWords(plan, s, start, end); err != nil {
+				// a failure of any worker outranks inconsistent shards
+				// reported by another, which only marks the stripe damaged
+				mu.Lock()
+				if stripeErr == nil || stripeErr == errInconsistentShards {
+					stripeErr = err
+				}
+				mu.Unlock()
+			}
+		})
+		if stripeErr != nil && stripeErr != errInconsistentShards {
+			return stripeErr
+		}
+		if err := fn(s, stripeErr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeWords decodes codewords of a stripe in [start, end).
+func (d *StreamDecoder) decodeWords(plan *DecodePlan, s *stripe, start, end int) error {
+	n, k := d.c.n, d.c.k
+	codeword := newEmptyPoly(n)
+	var stripeErr error
+	for j := start; j < end; j++ {
+		for i := 0; i < n; i++ {
+			codeword.a[i] = binary.LittleEndian.Uint64(s.shards[i][j*8:])
+		}
+		if err := plan.d.complete(codeword); err != nil {
+			return err
+		}
+		for _, i := range plan.missing {
+			binary.LittleEndian.PutUint64(s.shards[i][j*8:], codeword.a[i])
+		}
+		if _, err := codeword.lifft(); err != nil {
+			return err
+		}
+		if err := d.c.checkDegree(codeword); err != nil {
+			stripeErr = err
+		}
+		for t := 0; t < k; t++ {
+			binary.LittleEndian.PutUint64(s.data[(j*k+t)*8:], codeword.a[t])
+		}
+	}
+	return stripeErr
+}
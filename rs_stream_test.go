@@ -0,0 +1,204 @@
+package gf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func streamShards(t *testing.T, c *Codec, stripeSize int, input []byte) [][]byte {
+	e, err := NewStreamEncoder(c, stripeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bufs := make([]*bytes.Buffer, c.N())
+	ws := make([]io.Writer, c.N())
+	for i := range bufs {
+		bufs[i] = new(bytes.Buffer)
+		ws[i] = bufs[i]
+	}
+	size, err := e.Encode(bytes.NewReader(input), ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(input)) {
+		t.Fatalf("expected %d bytes to be read, got %d", len(input), size)
+	}
+	shards := make([][]byte, c.N())
+	for i := range bufs {
+		shards[i] = bufs[i].Bytes()
+	}
+	return shards
+}
+
+type failingReader struct {
+	r io.Reader
+	n int
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errors.New("shard is unavailable")
+	}
+	if len(p) > f.n {
+		p = p[:f.n]
+	}
+	l, err := f.r.Read(p)
+	f.n -= l
+	return l, err
+}
+
+func TestStreamRS(t *testing.T) {
+	n, k, stripeSize := 8, 5, 64
+	c, err := NewCodec(n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 319, 320, 321, 5000} {
+		input := make([]byte, size)
+		_, _ = rand.Read(input)
+		shards := streamShards(t, c, stripeSize, input)
+		stripes := (size + k*stripeSize - 1) / (k * stripeSize)
+		for i := range shards {
+			if len(shards[i]) != stripes*stripeSize {
+				t.Fatalf("bad shard length %d", len(shards[i]))
+			}
+		}
+		d, err := NewStreamDecoder(c, stripeSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs := make([]io.Reader, n)
+		for i := range rs {
+			rs[i] = bytes.NewReader(shards[i])
+		}
+		// two shards are missing, one fails after the first stripe
+		rs[1], rs[6] = nil, nil
+		rs[3] = &failingReader{rs[3], stripeSize}
+		out := new(bytes.Buffer)
+		if err := d.Decode(rs, out, int64(size)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), input) {
+			t.Fatal("stream decoding failed", size)
+		}
+	}
+}
+
+func TestStreamRSCodewords(t *testing.T) {
+	n, k, stripeSize := 8, 5, 256
+	c, err := NewCodec(n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make([]byte, 3*k*stripeSize)
+	_, _ = rand.Read(input)
+	shards := streamShards(t, c, stripeSize, input)
+	data := make([]uint64, k)
+	for j := 0; j < len(input)/(8*k); j++ {
+		for u := range data {
+			data[u] = binary.LittleEndian.Uint64(input[(j*k+u)*8:])
+		}
+		codeword, err := c.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			if binary.LittleEndian.Uint64(shards[i][j*8:]) != codeword[i] {
+				t.Fatal("stream codeword must match codec encoding", j, i)
+			}
+		}
+	}
+}
+
+func TestStreamRSNotEnoughShards(t *testing.T) {
+	n, k, stripeSize := 8, 5, 16
+	c, err := NewCodec(n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make([]byte, 1000)
+	_, _ = rand.Read(input)
+	shards := streamShards(t, c, stripeSize, input)
+	d, err := NewStreamDecoder(c, stripeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := make([]io.Reader, n)
+	for i := range rs {
+		rs[i] = bytes.NewReader(shards[i])
+	}
+	rs[0], rs[2], rs[4] = nil, nil, nil
+	rs[7] = &failingReader{rs[7], 2 * stripeSize}
+	if err := d.Decode(rs, io.Discard, int64(len(input))); err == nil {
+		t.Fatal("decoding must fail with less than k shards")
+	}
+}
+
+func BenchmarkStreamEncoding(b *testing.B) {
+	c, err := NewCodec(16, 10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	e, err := NewStreamEncoder(c, 1<<12)
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := make([]byte, 1<<20)
+	_, _ = rand.Read(input)
+	ws := make([]io.Writer, c.N())
+	for i := range ws {
+		ws[i] = io.Discard
+	}
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.Encode(bytes.NewReader(input), ws); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStreamRSVerify(t *testing.T) {
+	n, k, stripeSize := 8, 5, 16
+	c, err := NewCodec(n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make([]byte, 1000)
+	_, _ = rand.Read(input)
+	shards := streamShards(t, c, stripeSize, input)
+	d, err := NewStreamDecoder(c, stripeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(missing ...int) []int64 {
+		rs := make([]io.Reader, n)
+		for i := range rs {
+			rs[i] = bytes.NewReader(shards[i])
+		}
+		for _, i := range missing {
+			rs[i] = nil
+		}
+		inconsistent, err := d.Verify(rs, int64(len(input)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inconsistent
+	}
+	if len(verify()) != 0 {
+		t.Fatal("fresh shards must be consistent")
+	}
+	shards[3][2*stripeSize+5] ^= 1
+	shards[6][9*stripeSize] ^= 1
+	if fmt.Sprint(verify()) != "[2 9]" {
+		t.Fatal("inconsistent stripes must be reported")
+	}
+	if fmt.Sprint(verify(6)) != "[2]" {
+		t.Fatal("inconsistent stripes must be reported with erasures")
+	}
+}
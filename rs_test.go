@@ -44,6 +44,206 @@ func TestRS(t *testing.T) {
 	}
 }
 
+func TestRSAlignedBlockErasure(t *testing.T) {
+	initDefaultBasis(16)
+	m := 10
+	data := randPoly(1 << m)
+	encodedData, err := encode(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erasureData := encodedData.clone()
+	// lose four aligned blocks of size 2^8
+	missing := []uint64{}
+	for _, b := range []uint64{1, 3, 5, 6} {
+		for i := b << 8; i < (b+1)<<8; i++ {
+			missing = append(missing, i)
+			erasureData.a[i] = 0
+		}
+	}
+	if k := alignedBlockDimension(missing); k != 8 {
+		t.Fatal("aligned blocks are not detected", k)
+	}
+	recoveredData, err := recover(erasureData, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recoveredData.equalInCoeff(data) {
+		t.Fatal("rs recovery failed")
+	}
+}
+
+func TestRSAlignedBlockErasureAtZero(t *testing.T) {
+	initDefaultBasis(16)
+	m := 10
+	data := randPoly(1 << m)
+	encodedData, err := encode(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erasureData := encodedData.clone()
+	// block 0 holds the point zero, where Z(kx) vanished for every k
+	// before erasures were recovered with the derivative of Z
+	missing := []uint64{}
+	for _, b := range []uint64{0, 3, 5, 6} {
+		for i := b << 8; i < (b+1)<<8; i++ {
+			missing = append(missing, i)
+			erasureData.a[i] = 0
+		}
+	}
+	recoveredData, err := recover(erasureData, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recoveredData.equalInCoeff(data) {
+		t.Fatal("rs recovery failed")
+	}
+}
+
+func TestRSErasurePatterns(t *testing.T) {
+	initDefaultBasis(16)
+	for _, m := range []int{2, 3, 5} {
+		n := 1 << m
+		for _, k := range []int{1, n / 4, n / 2, n - 1} {
+			if k == 0 {
+				continue
+			}
+			data := randPoly(k)
+			encodedData := data.clone()
+			encodedData.expand(n)
+			if _, err := encodedData.fft(); err != nil {
+				t.Fatal(err)
+			}
+			for trial := 0; trial < 50; trial++ {
+				// pick up to n - k missing indices, first trial loses the first n - k
+				perm := make([]uint64, n)
+				for i := 0; i < n; i++ {
+					perm[i] = uint64(i)
+				}
+				if trial > 0 {
+					for i := n - 1; i > 0; i-- {
+						j := int(randGF64() % uint64(i+1))
+						perm[i], perm[j] = perm[j], perm[i]
+					}
+				}
+				missing := perm[:int(randGF64()%uint64(n-k+1))]
+				if trial == 0 {
+					missing = perm[:n-k]
+				}
+				erasureData := encodedData.clone()
+				for _, i := range missing {
+					erasureData.a[i] = 0
+				}
+				recoveredData, err := recover(erasureData, missing)
+				if err != nil {
+					t.Fatal(err, m, k, missing)
+				}
+				if recoveredData.length() > k || !recoveredData.equalInCoeff(data) {
+					t.Fatal("rs recovery failed", m, k, missing)
+				}
+			}
+		}
+	}
+}
+
+func TestRSErasureAllPatterns(t *testing.T) {
+	initDefaultBasis(16)
+	n, k := 8, 3
+	data := randPoly(k)
+	encodedData := data.clone()
+	encodedData.expand(n)
+	if _, err := encodedData.fft(); err != nil {
+		t.Fatal(err)
+	}
+	for mask := 0; mask < 1<<n; mask++ {
+		missing := []uint64{}
+		for i := 0; i < n; i++ {
+			if mask>>i&1 == 1 {
+				missing = append(missing, uint64(i))
+			}
+		}
+		erasureData := encodedData.clone()
+		for _, i := range missing {
+			erasureData.a[i] = 0
+		}
+		recoveredData, err := recover(erasureData, missing)
+		if len(missing) > n-k {
+			// message degree is not known to the decoder, a result is only
+			// expected to be the unique one with its degree
+			if err == nil && recoveredData.length()+len(missing) > n {
+				t.Fatal("recovery with too many erasures is not unique", missing)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err, missing)
+		}
+		if !recoveredData.equalInCoeff(data) {
+			t.Fatal("rs recovery failed", missing)
+		}
+	}
+}
+
+func TestRSNovelBasis(t *testing.T) {
+	initDefaultBasis(16)
+	m := 12
+	data := randPoly(1 << m)
+	encodedData, err := encodeNovel(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// novel basis codewords are the same as monomial ones
+	// for the message converted to monomial basis
+	monomialData := data.clone()
+	if _, err := monomialData.toMonomialBasis(); err != nil {
+		t.Fatal(err)
+	}
+	encodedData1, err := encode(monomialData, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !encodedData.equalInCoeff(encodedData1) {
+		t.Fatal("novel basis encoding failed")
+	}
+	for trial := 0; trial < 10; trial++ {
+		erasureData := encodedData.clone()
+		missing := []uint64{}
+		for i := 0; i < erasureData.length(); i++ {
+			if randGF64()&1 == 1 && len(missing) < 1<<m {
+				missing = append(missing, uint64(i))
+				erasureData.a[i] = 0
+			}
+		}
+		recoveredData, err := recoverNovel(erasureData, missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recoveredData.length() > 1<<m || !recoveredData.equalInCoeff(data) {
+			t.Fatal("rs recovery in novel basis failed")
+		}
+	}
+}
+
+func TestRSRecoverRejectsBadInput(t *testing.T) {
+	initDefaultBasis(16)
+	data := randPoly(4)
+	encodedData, err := encode(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erasureData := encodedData.clone()
+	erasureData.a[1] = 0
+	if _, err := recover(erasureData, []uint64{1, 1}); err == nil {
+		t.Fatal("repeated missing index must be rejected")
+	}
+	if _, err := recover(erasureData, []uint64{2}); err == nil {
+		t.Fatal("non zero erasure must be rejected")
+	}
+	if _, err := recover(erasureData, []uint64{8}); err == nil {
+		t.Fatal("out of range index must be rejected")
+	}
+}
+
 func BenchmarkRSEncoding(t *testing.B) {
 	m := polyLen
 	initDefaultBasis(m)
@@ -86,3 +286,132 @@ func BenchmarkRSDecoding(t *testing.B) {
 		}
 	}
 }
+
+func BenchmarkRSDecodingAlignedBlock(t *testing.B) {
+	m := polyLen
+	initDefaultBasis(m)
+	data := randPoly(1 << (m - 2))
+	encodedData, err := encode(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erasureData := encodedData.clone()
+	// bench with the second half of the data is missing
+	missingDataSize := 1 << (m - 2)
+	missing := make([]uint64, missingDataSize)
+	for i := 0; i < missingDataSize; i++ {
+		missing[i] = uint64(missingDataSize + i)
+		erasureData.a[missing[i]] = 0
+	}
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		_, err := recover(erasureData, missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRSEncodingNovel(t *testing.B) {
+	m := polyLen
+	initDefaultBasis(m)
+	data := randPoly(1 << (m - 2))
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		_, err := encodeNovel(data, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRSDecodingNovel(t *testing.B) {
+	m := polyLen
+	initDefaultBasis(m)
+	data := randPoly(1 << (m - 2))
+	encodedData, err := encodeNovel(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erasureData := encodedData.clone()
+	// bench with half of the data is missing
+	missingDataSize := 1 << (m - 2)
+	missing := make([]uint64, missingDataSize)
+	for i := 0; i < missingDataSize; i++ {
+		missing[i] = 2 + uint64(i)
+		erasureData.a[missing[i]] = 0
+	}
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		_, err := recoverNovel(erasureData, missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRSVerify(t *testing.T) {
+	initDefaultBasis(10)
+	k := 1 << 6
+	for _, b := range []polyBasis{monomialBasis, novelBasis} {
+		codeword, err := encodeIn(randPoly(k), 4, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := verifyIn(codeword, k, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("codeword must be consistent")
+		}
+		codeword.a[randGF64()%uint64(codeword.length())] ^= 1
+		ok, err = verifyIn(codeword, k, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Fatal("corrupt codeword must be inconsistent")
+		}
+	}
+}
+
+func TestRSUpdate(t *testing.T) {
+	initDefaultBasis(10)
+	k := 1 << 6
+	for _, b := range []polyBasis{monomialBasis, novelBasis} {
+		data := randPoly(k)
+		codeword, err := encodeIn(data, 4, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, index := range []int{0, 1, 17, k - 1} {
+			v := randGF64()
+			if err := updateIn(codeword, index, data.a[index], v, b); err != nil {
+				t.Fatal(err)
+			}
+			data.a[index] = v
+			expected, err := encodeIn(data, 4, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !expected.equalInCoeff(codeword) {
+				t.Fatal("updated codeword must equal to fresh encoding", index)
+			}
+		}
+	}
+}
+
+func BenchmarkRSUpdate(b *testing.B) {
+	initDefaultBasis(16)
+	codeword, err := encodeNovel(randPoly(1<<14), 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := updateNovel(codeword, i%(1<<14), 0, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
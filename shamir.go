@@ -0,0 +1,146 @@
+package gf
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Shamir secret sharing over GF(2^64). Secret is padded with 0x80 and zeros
+// to a multiple of 8 bytes and every 8 byte chunk is the constant term of a
+// random polynomial of degree t - 1. Share i is evaluation of polynomials
+// at x_i = combinations[i] of default basis for i in [1, n], as
+// combinations[0] = 0 is where the secret is. Polynomials are sampled in
+// novel polynomial basis, whose elements except X_0 = 1 vanish at zero, so
+// that all shares of a chunk are computed with a single additive FFT.
+
+// maxShares is the maximum number of shares.
+const maxShares = 1<<16 - 1
+
+// Share is a share of a secret.
+type Share struct {
+	// Index is in [1, n], share is evaluation at combinations[Index].
+	Index int
+	// Value holds evaluations of chunks in little endian.
+	Value []byte
+}
+
+// Split splits secret into n shares any t of which are enough to recover it.
+// Randomness is read from rand, crypto/rand if nil.
+func Split(secret []byte, n, t int, rand io.Reader) ([]Share, error) {
+	if n < 1 || n > maxShares {
+		return nil, fmt.Errorf("number of shares is expected to be in [1, %d]: %d", maxShares, n)
+	}
+	if t < 1 || t > n {
+		return nil, fmt.Errorf("threshold is expected to be in [1, %d]: %d", n, t)
+	}
+	if rand == nil {
+		rand = crand.Reader
+	}
+	padded := append(append([]byte{}, secret...), 0x80)
+	for len(padded)%8 != 0 {
+		padded = append(padded, 0)
+	}
+	chunks := len(padded) / 8
+	random := make([]byte, 8*(t-1)*chunks)
+	if _, err := io.ReadFull(rand, random); err != nil {
+		return nil, err
+	}
+
+	m := log2Ceil(n + 1)
+	ensureDefaultBasis(m)
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{Index: i + 1, Value: make([]byte, 8*chunks)}
+	}
+	p := newEmptyPoly(1 << m)
+	for c := 0; c < chunks; c++ {
+		for i := range p.a {
+			p.a[i] = 0
+		}
+		p.a[0] = binary.LittleEndian.Uint64(padded[8*c:])
+		for i := 1; i < t; i++ {
+			p.a[i] = binary.LittleEndian.Uint64(random[8*((t-1)*c+i-1):])
+		}
+		if _, err := p.lfft(); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			binary.LittleEndian.PutUint64(shares[i].Value[8*c:], p.a[i+1])
+		}
+	}
+	return shares, nil
+}
+
+// Combine recovers the secret from shares with Lagrange interpolation at
+// zero. With fewer shares than the threshold result is unrelated to the
+// secret and is likely to be rejected for bad padding.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no share is given")
+	}
+	size := len(shares[0].Value)
+	if size == 0 || size%8 != 0 {
+		return nil, fmt.Errorf("share length %d is expected to be a positive multiple of 8", size)
+	}
+	seen := map[int]bool{}
+	maxIndex := 0
+	for _, s := range shares {
+		if s.Index < 1 || s.Index > maxShares {
+			return nil, fmt.Errorf("share index %d is out of range", s.Index)
+		}
+		if seen[s.Index] {
+			return nil, fmt.Errorf("duplicate share index %d", s.Index)
+		}
+		seen[s.Index] = true
+		if len(s.Value) != size {
+			return nil, fmt.Errorf("share %d length %d is expected to be %d", s.Index, len(s.Value), size)
+		}
+		if s.Index > maxIndex {
+			maxIndex = s.Index
+		}
+	}
+	ensureDefaultBasis(log2Ceil(maxIndex + 1))
+	x := make([]uint64, len(shares))
+	for i, s := range shares {
+		x[i] = defaultBasis().combinations[s.Index]
+	}
+	// weight of share i is prod x_j / (x_i + x_j) over j != i
+	num := make([]uint64, len(x))
+	den := newEmptyPoly(len(x))
+	for i := range x {
+		num[i], den.a[i] = 1, 1
+		for j := range x {
+			if j != i {
+				mulassign64(&num[i], x[j])
+				mulassign64(&den.a[i], x[i]^x[j])
+			}
+		}
+	}
+	if _, err := den.invSample(); err != nil {
+		return nil, err
+	}
+	weights := make([]uint64, len(x))
+	for i := range weights {
+		weights[i] = mul64(num[i], den.a[i])
+	}
+	padded := make([]byte, size)
+	for c := 0; c < size/8; c++ {
+		var s uint64
+		for i, share := range shares {
+			y := binary.LittleEndian.Uint64(share.Value[8*c:])
+			s ^= mul64(y, weights[i])
+		}
+		binary.LittleEndian.PutUint64(padded[8*c:], s)
+	}
+	end := len(padded) - 1
+	for end >= len(padded)-8 && padded[end] == 0 {
+		end--
+	}
+	if end < len(padded)-8 || padded[end] != 0x80 {
+		return nil, errors.New("bad padding, shares are not enough or corrupted")
+	}
+	return padded[:end], nil
+}
@@ -0,0 +1,103 @@
+package gf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"math/bits"
+	"testing"
+)
+
+func TestShamir(t *testing.T) {
+	n := 5
+	for _, size := range []int{0, 1, 7, 8, 33} {
+		secret := make([]byte, size)
+		_, _ = rand.Read(secret)
+		for th := 1; th <= n; th++ {
+			shares, err := Split(secret, n, th, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// every subset of shares
+			for mask := 1; mask < 1<<n; mask++ {
+				subset := []Share{}
+				for i := 0; i < n; i++ {
+					if mask>>i&1 == 1 {
+						subset = append(subset, shares[i])
+					}
+				}
+				got, err := Combine(subset)
+				if bits.OnesCount(uint(mask)) >= th {
+					if err != nil {
+						t.Fatal(err, size, th, mask)
+					}
+					if !bytes.Equal(got, secret) {
+						t.Fatal("secret must be recovered", size, th, mask)
+					}
+				} else if err == nil && bytes.Equal(got, secret) {
+					t.Fatal("secret must not be recovered below threshold", size, th, mask)
+				}
+			}
+		}
+	}
+}
+
+func TestShamirSharesAreEvaluations(t *testing.T) {
+	n, th := 9, 4
+	secret := []byte("binary fields")
+	random := make([]byte, 8*(th-1)*2)
+	_, _ = rand.Read(random)
+	shares, err := Split(secret, n, th, bytes.NewReader(random))
+	if err != nil {
+		t.Fatal(err)
+	}
+	padded := append(append([]byte{}, secret...), 0x80, 0, 0)
+	for c := 0; c < 2; c++ {
+		p := newEmptyPoly(th)
+		p.a[0] = binary.LittleEndian.Uint64(padded[8*c:])
+		for i := 1; i < th; i++ {
+			p.a[i] = binary.LittleEndian.Uint64(random[8*((th-1)*c+i-1):])
+		}
+		if _, err := p.toMonomialBasis(); err != nil {
+			t.Fatal(err)
+		}
+		if p.evalSingle(0) != binary.LittleEndian.Uint64(padded[8*c:]) {
+			t.Fatal("polynomial must hide the secret at zero")
+		}
+		for _, s := range shares {
+			y := binary.LittleEndian.Uint64(s.Value[8*c:])
+			if p.evalSingle(defaultBasis().combinations[s.Index]) != y {
+				t.Fatal("share must be evaluation at its point", s.Index)
+			}
+		}
+	}
+}
+
+func TestShamirRejects(t *testing.T) {
+	shares, err := Split([]byte("secret"), 4, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Combine([]Share{shares[0], shares[0]}); err == nil {
+		t.Fatal("duplicate shares must be rejected")
+	}
+	zero := Share{Index: 0, Value: shares[1].Value}
+	if _, err := Combine([]Share{zero, shares[1]}); err == nil {
+		t.Fatal("share at zero must be rejected")
+	}
+	short := Share{Index: 3, Value: shares[2].Value[:4]}
+	if _, err := Combine([]Share{shares[0], short}); err == nil {
+		t.Fatal("shares of different lengths must be rejected")
+	}
+	if _, err := Combine(nil); err == nil {
+		t.Fatal("no share must be rejected")
+	}
+	for _, p := range [][2]int{{0, 1}, {3, 0}, {3, 4}, {maxShares + 1, 2}} {
+		if _, err := Split([]byte("secret"), p[0], p[1], nil); err == nil {
+			t.Fatal("bad parameters must be rejected", p)
+		}
+	}
+	if _, err := Split([]byte("secret"), 4, 3, bytes.NewReader(make([]byte, 8))); err == nil {
+		t.Fatal("short randomness must be rejected")
+	}
+}
@@ -0,0 +1,215 @@
+package gf
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// Sumcheck proves the sum of a product of d multilinear polynomials over
+// the boolean hypercube, sum_x f_1(x) ... f_d(x). In each round prover sends
+// the round polynomial s(t) of degree d, summing over the remaining
+// variables with the last free variable set to t, as evaluations at
+// t = 0, 1, ..., d taken as field elements. Verifier checks s(0) + s(1)
+// against the running claim, draws a challenge r and continues with s(r).
+// At the end claim must equal the product of f_j at the random point,
+// whose evaluations are sent by the prover to be checked with an oracle
+// or a commitment.
+//
+// Polynomials are over GF(2^64) while challenges are drawn from the tower
+// extension GF(2^128) for 128 bits of soundness, d v / 2^128 for v variables.
+// First round runs in the base field and folded polynomials are in the
+// extension. Challenges are derived with Fiat-Shamir.
+
+type sumcheckProof struct {
+	// rounds are evaluations of round polynomials at 0, 1, ..., d
+	rounds [][]tower128
+	// finals are evaluations of factors at the random point
+	finals []tower128
+}
+
+func sumcheckTranscript(h func() hash.Hash, v, d int, claim uint64) *transcript {
+	if h == nil {
+		h = sha256.New
+	}
+	t := newTranscript(h, "gf sumcheck")
+	t.absorbSymbols([]uint64{uint64(v), uint64(d), claim})
+	return t
+}
+
+func (t *transcript) absorbTower(values []tower128) {
+	symbols := make([]uint64, 0, 2*len(values))
+	for _, v := range values {
+		symbols = append(symbols, v[0], v[1])
+	}
+	t.absorbSymbols(symbols)
+}
+
+func (t *transcript) challengeTower() tower128 {
+	return tower128{t.challenge(), t.challenge()}
+}
+
+// proveSumcheck returns the sum of product of factors, the proof and the
+// random point at which factors are evaluated, point[j] being bound to variable j.
+func proveSumcheck(factors []*multilinear, h func() hash.Hash) (uint64, *sumcheckProof, []tower128, error) {
+	d := len(factors)
+	if d == 0 {
+		return 0, nil, nil, errors.New("no polynomial to sum")
+	}
+	n := len(factors[0].evals)
+	for _, f := range factors {
+		if len(f.evals) != n {
+			return 0, nil, nil, errors.New("polynomials are expected to have same number of variables")
+		}
+	}
+	v := factors[0].vars()
+
+	product := newPoly(append([]uint64{}, factors[0].evals...))
+	for _, f := range factors[1:] {
+		if _, err := product.mulSample(newPoly(f.evals)); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	claim := (&multilinear{evals: product.a}).sum()
+
+	t := sumcheckTranscript(h, v, d, claim)
+	proof := &sumcheckProof{}
+	point := make([]tower128, v)
+	if v == 0 {
+		for _, f := range factors {
+			proof.finals = append(proof.finals, tower128{f.evals[0], 0})
+		}
+		return claim, proof, point, nil
+	}
+
+	// first round over the base field
+	s := make([]tower128, d+1)
+	half := n / 2
+	for x := 0; x < half; x++ {
+		for e := 0; e <= d; e++ {
+			p := uint64(1)
+			for _, f := range factors {
+				lo, hi := f.evals[x], f.evals[half+x]
+				mulassign64(&p, lo^mul64(uint64(e), lo^hi))
+			}
+			s[e][0] ^= p
+		}
+	}
+	proof.rounds = append(proof.rounds, s)
+	t.absorbTower(s)
+	r := t.challengeTower()
+	point[v-1] = r
+	tables := make([]*multilinearExt, d)
+	for j, f := range factors {
+		tables[j] = f.foldExt(r)
+	}
+
+	for round := 1; round < v; round++ {
+		s := make([]tower128, d+1)
+		half := len(tables[0].evals) / 2
+		for x := 0; x < half; x++ {
+			for e := 0; e <= d; e++ {
+				p := tower128{1, 0}
+				for _, f := range tables {
+					lo, hi := f.evals[x], f.evals[half+x]
+					diff := tower128{lo[0] ^ hi[0], lo[1] ^ hi[1]}
+					// lo + e (lo + hi) with e in the base field
+					p = mulTower(p, tower128{lo[0] ^ mul64(uint64(e), diff[0]), lo[1] ^ mul64(uint64(e), diff[1])})
+				}
+				s[e][0] ^= p[0]
+				s[e][1] ^= p[1]
+			}
+		}
+		proof.rounds = append(proof.rounds, s)
+		t.absorbTower(s)
+		r := t.challengeTower()
+		point[v-1-round] = r
+		for j := range tables {
+			tables[j] = tables[j].fold(r)
+		}
+	}
+	for _, f := range tables {
+		proof.finals = append(proof.finals, f.evals[0])
+	}
+	return claim, proof, point, nil
+}
+
+// verifySumcheck checks a proof of the claimed sum of product of d
+// polynomials in v variables and returns the random point. Caller is
+// expected to check that proof.finals are evaluations of factors at the point.
+func verifySumcheck(claim uint64, v, d int, proof *sumcheckProof, h func() hash.Hash) ([]tower128, error) {
+	if len(proof.rounds) != v || len(proof.finals) != d {
+		return nil, fmt.Errorf("expected %d rounds and %d final evaluations", v, d)
+	}
+	t := sumcheckTranscript(h, v, d, claim)
+	current := tower128{claim, 0}
+	point := make([]tower128, v)
+	weights, err := lagrangeWeights(d)
+	if err != nil {
+		return nil, err
+	}
+	for round, s := range proof.rounds {
+		if len(s) != d+1 {
+			return nil, fmt.Errorf("round %d polynomial is expected to have %d evaluations", round, d+1)
+		}
+		if s[0][0]^s[1][0] != current[0] || s[0][1]^s[1][1] != current[1] {
+			return nil, fmt.Errorf("round %d sum is inconsistent", round)
+		}
+		t.absorbTower(s)
+		r := t.challengeTower()
+		point[v-1-round] = r
+		current = interpolateRound(s, weights, r)
+	}
+	p := tower128{1, 0}
+	for _, f := range proof.finals {
+		p = mulTower(p, f)
+	}
+	if p != current {
+		return nil, errors.New("final evaluations are inconsistent")
+	}
+	return point, nil
+}
+
+// lagrangeWeights returns 1 / prod (i - j) over j != i for nodes 0, 1, ..., d
+// with a single batch inversion.
+func lagrangeWeights(d int) ([]uint64, error) {
+	den := newEmptyPoly(d + 1)
+	for i := 0; i <= d; i++ {
+		den.a[i] = 1
+		for j := 0; j <= d; j++ {
+			if j != i {
+				mulassign64(&den.a[i], uint64(i^j))
+			}
+		}
+	}
+	if _, err := den.invSample(); err != nil {
+		return nil, err
+	}
+	return den.a, nil
+}
+
+// interpolateRound evaluates at r the polynomial of degree d given by
+// evaluations at 0, 1, ..., d, sum s_i w_i prod (r - j) over j != i,
+// where products are taken with prefix and suffix products.
+func interpolateRound(s []tower128, weights []uint64, r tower128) tower128 {
+	n := len(s)
+	prefix := make([]tower128, n+1)
+	suffix := make([]tower128, n+1)
+	prefix[0], suffix[n] = tower128{1, 0}, tower128{1, 0}
+	for j := 0; j < n; j++ {
+		prefix[j+1] = mulTower(prefix[j], tower128{r[0] ^ uint64(j), r[1]})
+	}
+	for j := n - 1; j >= 0; j-- {
+		suffix[j] = mulTower(suffix[j+1], tower128{r[0] ^ uint64(j), r[1]})
+	}
+	var acc tower128
+	for i := 0; i < n; i++ {
+		l := mulTower(prefix[i], suffix[i+1])
+		c := mulTower(s[i], tower128{weights[i], 0})
+		p := mulTower(l, c)
+		acc[0] ^= p[0]
+		acc[1] ^= p[1]
+	}
+	return acc
+}
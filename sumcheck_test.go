@@ -0,0 +1,120 @@
+package gf
+
+import (
+	"crypto/sha512"
+	"testing"
+)
+
+func randFactors(v, d int) []*multilinear {
+	factors := make([]*multilinear, d)
+	for j := range factors {
+		factors[j] = randMultilinear(v)
+	}
+	return factors
+}
+
+func TestSumcheck(t *testing.T) {
+	for _, d := range []int{1, 2, 3} {
+		for _, v := range []int{0, 1, 5, 8} {
+			factors := randFactors(v, d)
+			claim, proof, point, err := proveSumcheck(factors, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want uint64
+			for x := 0; x < 1<<v; x++ {
+				p := uint64(1)
+				for _, f := range factors {
+					mulassign64(&p, f.evals[x])
+				}
+				want ^= p
+			}
+			if claim != want {
+				t.Fatal("claim must be the sum over the hypercube", d, v)
+			}
+			vpoint, err := verifySumcheck(claim, v, d, proof, nil)
+			if err != nil {
+				t.Fatal(err, d, v)
+			}
+			for j := range point {
+				if point[j] != vpoint[j] {
+					t.Fatal("prover and verifier must agree on the point", d, v)
+				}
+			}
+			for j, f := range factors {
+				e, err := f.evaluateExt(vpoint)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if e != proof.finals[j] {
+					t.Fatal("final evaluations must be evaluations of factors", d, v)
+				}
+			}
+		}
+	}
+}
+
+func TestSumcheckRejects(t *testing.T) {
+	v, d := 6, 3
+	factors := randFactors(v, d)
+	claim, proof, _, err := proveSumcheck(factors, sha512.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifySumcheck(claim, v, d, proof, sha512.New); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifySumcheck(claim^1, v, d, proof, sha512.New); err == nil {
+		t.Fatal("wrong claim must be rejected")
+	}
+	if _, err := verifySumcheck(claim, v, d, proof, nil); err == nil {
+		t.Fatal("proof must be rejected with another hash")
+	}
+	if _, err := verifySumcheck(claim, v-1, d, proof, sha512.New); err == nil {
+		t.Fatal("wrong number of variables must be rejected")
+	}
+	// a consistent change of a round polynomial changes challenges
+	proof.rounds[2][0][1] ^= 1
+	proof.rounds[2][1][1] ^= 1
+	if _, err := verifySumcheck(claim, v, d, proof, sha512.New); err == nil {
+		t.Fatal("tampered round must be rejected")
+	}
+	proof.rounds[2][0][1] ^= 1
+	proof.rounds[2][1][1] ^= 1
+	proof.finals[1][0] ^= 1
+	if _, err := verifySumcheck(claim, v, d, proof, sha512.New); err == nil {
+		t.Fatal("tampered final evaluation must be rejected")
+	}
+	if _, _, _, err := proveSumcheck([]*multilinear{randMultilinear(3), randMultilinear(4)}, nil); err == nil {
+		t.Fatal("factors of different sizes must be rejected")
+	}
+}
+
+func TestInterpolateRound(t *testing.T) {
+	// s(t) = a + b t + c t^2 in the extension
+	a, b, c := randTower128(), randTower128(), randTower128()
+	eval := func(x tower128) tower128 {
+		r := mulTower(mulTower(c, x), x)
+		bx := mulTower(b, x)
+		return tower128{a[0] ^ bx[0] ^ r[0], a[1] ^ bx[1] ^ r[1]}
+	}
+	s := []tower128{eval(tower128{0, 0}), eval(tower128{1, 0}), eval(tower128{2, 0})}
+	weights, err := lagrangeWeights(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := randTower128()
+	if interpolateRound(s, weights, r) != eval(r) {
+		t.Fatal("interpolation must recover the polynomial")
+	}
+}
+
+func BenchmarkSumcheckProve(t *testing.B) {
+	factors := randFactors(16, 2)
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if _, _, _, err := proveSumcheck(factors, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
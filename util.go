@@ -1,5 +1,10 @@
 package gf
 
+import (
+	"runtime"
+	"sync"
+)
+
 func log2Floor(n int) int {
 	if n == 0 {
 		panic("log 0")
@@ -28,3 +33,29 @@ func log2Ceil(n int) int {
 		r += 1
 	}
 }
+
+// parallel splits [0, n) into chunks and runs fn over them concurrently.
+func parallel(n int, fn func(start, end int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,37 @@
+#include "textflag.h"
+
+// r = x^7 + x^2 + x + 1, lower part of x^128 + r
+DATA P128<>+0(SB)/8, $0x87
+GLOBL P128<>(SB), RODATA|NOPTR, $8
+
+
+TEXT ·mul128(SB), NOSPLIT, $0-24
+  MOVQ a+8(FP), AX
+  MOVQ b+16(FP), BX
+  MOVOU (AX), X0
+  MOVOU (BX), X1
+  // c1, c0 = a0 * b0
+  VPCLMULQDQ $0x00, X1, X0, X2
+  // c3, c2 = a1 * b1
+  VPCLMULQDQ $0x11, X1, X0, X3
+  // middle = a0 * b1 + a1 * b0
+  VPCLMULQDQ $0x10, X1, X0, X4
+  VPCLMULQDQ $0x01, X1, X0, X5
+  PXOR X5, X4
+  VPSLLDQ $8, X4, X5
+  VPSRLDQ $8, X4, X4
+  PXOR X5, X2
+  PXOR X4, X3
+  // t1, t0 = c3 * r and c2, c1 += t1, t0
+  MOVQ P128<>+0(SB), X6
+  VPCLMULQDQ $0x01, X6, X3, X7
+  VPSLLDQ $8, X7, X8
+  PXOR X8, X2
+  VPSRLDQ $8, X7, X7
+  PXOR X7, X3
+  // u1, u0 = c2 * r and c1, c0 += u1, u0
+  VPCLMULQDQ $0x00, X6, X3, X7
+  PXOR X7, X2
+  MOVQ c+0(FP), AX
+  MOVOU X2, (AX)
+  RET